@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/youbuwei/doeot-go/internal/tools/bizgen"
 )
 
 func main() {
-	_ = bizgen.NewCommand().Run(context.Background(), os.Args[1:])
+	if err := bizgen.NewCommand().Run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -17,6 +17,7 @@ func main() {
 	app.Register(dev.NewCommand())
 	app.Register(modgen.NewCommand())
 	app.Register(bizgen.NewCommand())
+	app.Register(bizgen.NewOpenAPICommand())
 
 	// 将来这里还可以注册业务模块的命令:
 	// app.RegisterProvider(usercmd.NewUserCommands())
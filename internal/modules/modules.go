@@ -1,5 +1,7 @@
 package modules
 
+//go:generate go run github.com/youbuwei/doeot-go/cmd/doeot openapi -o "" -go internal/modules/zz_openapi_gen.go
+
 import (
 	"github.com/youbuwei/doeot-go/pkg/biz"
 	"gorm.io/gorm"
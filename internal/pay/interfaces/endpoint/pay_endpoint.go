@@ -58,6 +58,7 @@ func (e *PayEndpoint) GetPay(ctx biz.Context, req *GetPayReq) (*GetPayResp, erro
 // @Auth   login
 // @Desc   创建 pay
 // @Tags   pay
+// @Idempotent ttl=24h
 func (e *PayEndpoint) CreatePay(ctx biz.Context, req *CreatePayReq) (*CreatePayResp, error) {
 	m, err := e.Svc.Create(ctx.RequestContext(), &domain.Pay{
 		Name: req.Name,
@@ -0,0 +1,78 @@
+package bizgen
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 生成 RPC 强类型客户端：internal/<module>/interfaces/rpcclient/zz_client_gen.go。
+func generateClient(res *scanResult, module string) error {
+	imports := map[string]string{}
+
+	var methods []clientMethodData
+	for _, e := range res.Endpoints {
+		if e.RPCMethod == "" {
+			continue
+		}
+		if e.ReqType.Expr == "" || e.RespType.Expr == "" {
+			log.Printf("bizgen: skip rpc client method %s: unsupported signature", e.MethodName)
+			continue
+		}
+		for name, p := range e.ReqType.Imports {
+			imports[name] = p
+		}
+		for name, p := range e.RespType.Imports {
+			imports[name] = p
+		}
+
+		m := clientMethodData{
+			MethodName: e.MethodName,
+			RPCMethod:  e.RPCMethod,
+			ReqType:    e.ReqType.Expr,
+			RespType:   e.RespType.Expr,
+		}
+		if strings.HasPrefix(m.RespType, "*") {
+			m.RespElem = strings.TrimPrefix(m.RespType, "*")
+		}
+		methods = append(methods, m)
+	}
+
+	if len(methods) == 0 {
+		return nil
+	}
+
+	var lines []string
+	for name, p := range imports {
+		if name == path.Base(p) {
+			lines = append(lines, strconv.Quote(p))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s %q", name, p))
+		}
+	}
+	sort.Strings(lines)
+
+	data := clientTemplateData{
+		ModPath: res.ModPath,
+		Module:  module,
+		Imports: lines,
+		Methods: methods,
+	}
+
+	clientDir := filepath.Join(res.RootDir, "internal", module, "interfaces", "rpcclient")
+	if err := os.MkdirAll(clientDir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(clientDir, "zz_client_gen.go"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return clientTmpl.Execute(f, data)
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // 生成 HTTP 路由包装代码。
@@ -16,19 +18,28 @@ func generateHTTP(res *scanResult, module string) error {
 	}
 
 	var eps []httpEndpointData
+	importTime := false
 	for _, e := range res.Endpoints {
 		if e.RouteMethod == "" || e.RoutePath == "" {
 			continue
 		}
 		method := strings.ToUpper(e.RouteMethod)
 		switch method {
-		case "GET", "POST", "PUT", "DELETE":
+		case "GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS", "ANY":
 		default:
-			continue
+			return fmt.Errorf("bizgen: %s: 不支持的 HTTP 方法 %q", e.MethodName, e.RouteMethod)
+		}
+
+		bizTag := bizTagOf(module, e.MethodName)
+		opts := buildOptions(e, bizTag)
+		if e.Upload > 0 {
+			opts += fmt.Sprintf(", biz.WithUpload(%d)", e.Upload)
+		}
+		if e.Produces != "" {
+			opts += fmt.Sprintf(", biz.WithProduces(%q)", e.Produces)
 		}
 
-		bizTag := strings.ToLower(module + "." + strings.ToLower(e.MethodName))
-		opts := buildOptions(e.Auth, e.Tags, bizTag)
+		importTime = importTime || usesTime(e)
 
 		eps = append(eps, httpEndpointData{
 			MethodName: e.MethodName,
@@ -46,6 +57,8 @@ func generateHTTP(res *scanResult, module string) error {
 		ModPath:      res.ModPath,
 		Module:       module,
 		EndpointType: endpointType,
+		Prefix:       res.Prefixes[endpointType],
+		ImportTime:   importTime,
 		Endpoints:    eps,
 	}
 
@@ -63,20 +76,84 @@ func generateHTTP(res *scanResult, module string) error {
 	return httpTmpl.Execute(f, data)
 }
 
-// 构造中间件 Options 字符串，例如：
+// bizTagOf 返回端点的业务标识，例如 "user.getuser"。
+func bizTagOf(module, method string) string {
+	return strings.ToLower(module + "." + method)
+}
+
+// 构造 HTTP / RPC 共用的 Options 字符串，例如：
 // "biz.WithAuth("login"), biz.WithTags("user"), biz.WithBizTag("user.getuser")"
-func buildOptions(auth string, tags []string, bizTag string) string {
+func buildOptions(e endpointInfo, bizTag string) string {
 	var opts []string
-	if auth != "" {
-		opts = append(opts, fmt.Sprintf("biz.WithAuth(%q)", auth))
+	if e.Auth != "" {
+		opts = append(opts, fmt.Sprintf("biz.WithAuth(%q)", e.Auth))
 	}
-	if len(tags) > 0 {
-		qs := make([]string, 0, len(tags))
-		for _, t := range tags {
-			qs = append(qs, fmt.Sprintf("%q", t))
-		}
-		opts = append(opts, fmt.Sprintf("biz.WithTags(%s)", strings.Join(qs, ", ")))
+	if len(e.Tags) > 0 {
+		opts = append(opts, fmt.Sprintf("biz.WithTags(%s)", quoteList(e.Tags)))
 	}
 	opts = append(opts, fmt.Sprintf("biz.WithBizTag(%q)", bizTag))
+	if e.Desc != "" {
+		opts = append(opts, fmt.Sprintf("biz.WithDesc(%q)", e.Desc))
+	}
+	if len(e.Middleware) > 0 {
+		opts = append(opts, fmt.Sprintf("biz.WithMiddleware(%s)", quoteList(e.Middleware)))
+	}
+	if rl := e.RateLimit; rl != nil {
+		opts = append(opts, fmt.Sprintf("biz.WithRateLimit(%d, %s, %d, %q)", rl.Limit, rl.Period, rl.Burst, rl.Key))
+	}
+	if e.Idempotent > 0 {
+		opts = append(opts, fmt.Sprintf("biz.WithIdempotency(%s)", durationExpr(e.Idempotent, false)))
+	}
+	if e.Timeout > 0 {
+		opts = append(opts, fmt.Sprintf("biz.WithTimeout(%s)", durationExpr(e.Timeout, false)))
+	}
+	if cb := e.CircuitBreaker; cb != nil {
+		fields := []string{
+			"FailureRatio: " + strconv.FormatFloat(cb.FailureRatio, 'g', -1, 64),
+			"Window: " + durationExpr(cb.Window, true),
+		}
+		if cb.MinRequests > 0 {
+			fields = append(fields, fmt.Sprintf("MinRequests: %d", cb.MinRequests))
+		}
+		if cb.Cooldown > 0 {
+			fields = append(fields, "Cooldown: "+durationExpr(cb.Cooldown, true))
+		}
+		opts = append(opts, fmt.Sprintf("biz.WithCircuitBreaker(biz.CircuitBreaker{%s})", strings.Join(fields, ", ")))
+	}
 	return strings.Join(opts, ", ")
 }
+
+// usesTime 报告 buildOptions 生成的代码是否引用了 time 包。
+func usesTime(e endpointInfo) bool {
+	return e.RateLimit != nil || e.Idempotent > 0 || e.Timeout > 0 || e.CircuitBreaker != nil
+}
+
+// durationExpr 将时长写成可读的 Go 表达式，例如 24*time.Hour。
+// gofmt 在调用参数中省略乘号两侧的空格、在复合字面量中保留，spaced 用于后者。
+func durationExpr(d time.Duration, spaced bool) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{{time.Hour, "time.Hour"}, {time.Minute, "time.Minute"}, {time.Second, "time.Second"}, {time.Millisecond, "time.Millisecond"}}
+	op := "*"
+	if spaced {
+		op = " * "
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			if d == u.unit {
+				return u.name
+			}
+			return fmt.Sprintf("%d%s%s", d/u.unit, op, u.name)
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", int64(d))
+}
+
+func quoteList(list []string) string {
+	qs := make([]string, 0, len(list))
+	for _, s := range list {
+		qs = append(qs, fmt.Sprintf("%q", s))
+	}
+	return strings.Join(qs, ", ")
+}
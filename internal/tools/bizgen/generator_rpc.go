@@ -1,9 +1,12 @@
 package bizgen
 
 import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
 	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
 )
 
 // 生成 RPC 包装代码。
@@ -14,12 +17,29 @@ func generateRPC(res *scanResult, module string) error {
 	}
 
 	var eps []rpcEndpointData
+	importTime := false
 	for _, e := range res.Endpoints {
 		if e.RPCMethod == "" {
 			continue
 		}
-		bizTag := strings.ToLower(module + "." + strings.ToLower(e.MethodName))
-		opts := buildOptions(e.Auth, e.Tags, bizTag)
+		if e.Upload > 0 || hasFileField(res, e) {
+			return fmt.Errorf("bizgen: %s: 上传接口（@Upload / biz.File）不支持 @RPC %s", e.MethodName, e.RPCMethod)
+		}
+		if kind := rawResponseKind(e.RespExpr, e.Imports); kind != "" {
+			return fmt.Errorf("bizgen: %s: 返回 biz.%s 的接口不支持 @RPC %s", e.MethodName, kind, e.RPCMethod)
+		}
+		if e.Produces != "" && !isJSONMediaType(e.Produces) {
+			return fmt.Errorf("bizgen: %s: @Produces %s 的接口不支持 @RPC %s", e.MethodName, e.Produces, e.RPCMethod)
+		}
+		bizTag := bizTagOf(module, e.MethodName)
+		opts := buildOptions(e, bizTag)
+		params, result, err := rpcSchemas(module, res.Types, e)
+		if err != nil {
+			return err
+		}
+		opts += fmt.Sprintf(", biz.WithSchema(%s, %s)", strconv.Quote(params), strconv.Quote(result))
+
+		importTime = importTime || usesTime(e)
 
 		eps = append(eps, rpcEndpointData{
 			MethodName: e.MethodName,
@@ -36,6 +56,7 @@ func generateRPC(res *scanResult, module string) error {
 		ModPath:      res.ModPath,
 		Module:       module,
 		EndpointType: endpointType,
+		ImportTime:   importTime,
 		Endpoints:    eps,
 	}
 
@@ -52,3 +73,54 @@ func generateRPC(res *scanResult, module string) error {
 
 	return rpcTmpl.Execute(f, data)
 }
+
+// rpcSchemas 生成 rpc.discover 使用的 params/result JSON Schema。
+// 每个 schema 自包含：引用的本包类型放在同一文档的 "$defs" 中。
+func rpcSchemas(module string, types map[string]*typeDecl, e endpointInfo) (string, string, error) {
+	// RPC wrapper 总是把 params 解码到 <Method>Req。
+	req := e.ReqExpr
+	if req == nil {
+		req = ast.NewIdent(e.MethodName + "Req")
+	}
+	params, err := selfContainedSchema(module, types, req, e.Imports)
+	if err != nil {
+		return "", "", err
+	}
+	if e.RespExpr == nil {
+		return params, "", nil
+	}
+	result, err := selfContainedSchema(module, types, e.RespExpr, e.Imports)
+	if err != nil {
+		return "", "", err
+	}
+	return params, result, nil
+}
+
+func selfContainedSchema(module string, types map[string]*typeDecl, expr ast.Expr, imports map[string]string) (string, error) {
+	b := newSchemaBuilder(module, types, "#/$defs/", nil)
+	s := b.schemaOf(expr, imports)
+	if len(b.defs) > 0 {
+		s = copySchema(s)
+		s["$defs"] = b.defs
+	}
+	out, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// hasFileField 判断 Req 结构是否包含 biz.File 字段（含切片与指针）。
+func hasFileField(res *scanResult, e endpointInfo) bool {
+	b := newSchemaBuilder("", res.Types, "", nil)
+	st, imports := b.requestStruct(e)
+	if st == nil {
+		return false
+	}
+	for _, f := range b.fields(st, imports) {
+		if f.File {
+			return true
+		}
+	}
+	return false
+}
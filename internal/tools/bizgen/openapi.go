@@ -0,0 +1,477 @@
+package bizgen
+
+import (
+	"encoding/json"
+	"go/ast"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/youbuwei/doeot-go/internal/tools/shared"
+)
+
+// OpenAPIConfig 是 OpenAPI 文档生成的配置。
+type OpenAPIConfig struct {
+	Modules []string // 为空表示扫描 internal/*/interfaces/endpoint 下的全部模块
+	Title   string
+	Version string
+}
+
+// BuildOpenAPI 扫描模块注解与 Req/Resp 类型，返回 OpenAPI 3.1 JSON 文档。
+func BuildOpenAPI(cfg OpenAPIConfig) ([]byte, error) {
+	modules := cfg.Modules
+	if len(modules) == 0 {
+		root, err := shared.FindRepoRoot()
+		if err != nil {
+			return nil, err
+		}
+		modules, err = discoverModules(root)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	doc := newOpenAPIDoc(cfg.Title, cfg.Version)
+	for _, m := range modules {
+		res, err := scanEndpoints(m)
+		if err != nil {
+			return nil, err
+		}
+		doc.addModule(m, res)
+	}
+	doc.finish()
+	return json.MarshalIndent(doc.root, "", "  ")
+}
+
+// discoverModules 返回包含 interfaces/endpoint 目录的模块名。
+func discoverModules(root string) ([]string, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "internal", "*", "interfaces", "endpoint"))
+	if err != nil {
+		return nil, err
+	}
+	var mods []string
+	for _, d := range dirs {
+		if fi, err := os.Stat(d); err == nil && fi.IsDir() {
+			mods = append(mods, filepath.Base(filepath.Dir(filepath.Dir(d))))
+		}
+	}
+	sort.Strings(mods)
+	return mods, nil
+}
+
+// openAPIDoc 以 map 形式组装文档，json 编码时 key 有序，输出稳定。
+type openAPIDoc struct {
+	root    map[string]any
+	paths   map[string]any
+	schemas map[string]any
+	secs    map[string]any
+	tags    map[string]bool
+}
+
+func newOpenAPIDoc(title, version string) *openAPIDoc {
+	if title == "" {
+		title = "doeot API"
+	}
+	if version == "" {
+		version = "0.0.0"
+	}
+	d := &openAPIDoc{
+		paths:   map[string]any{},
+		schemas: map[string]any{},
+		secs:    map[string]any{},
+		tags:    map[string]bool{},
+	}
+	d.schemas["Error"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"code":       map[string]any{"type": "string", "description": "业务错误码，如 BAD_REQUEST"},
+			"msg":        map[string]any{"type": "string"},
+			"request_id": map[string]any{"type": "string"},
+			"details":    map[string]any{"$ref": "#/components/schemas/ErrorDetails"},
+		},
+		"required": []string{"code", "msg"},
+	}
+	d.schemas["ErrorDetails"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"violations": map[string]any{
+				"type":        "array",
+				"description": "字段校验失败列表",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"field":   map[string]any{"type": "string"},
+						"rule":    map[string]any{"type": "string"},
+						"message": map[string]any{"type": "string"},
+					},
+					"required": []string{"field", "rule", "message"},
+				},
+			},
+			"retry": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"after_seconds": map[string]any{"type": "integer", "description": "建议重试间隔（秒）"},
+				},
+			},
+			"metadata": map[string]any{"type": "object", "additionalProperties": true},
+		},
+	}
+	d.root = map[string]any{
+		"openapi": "3.1.0",
+		"info":    map[string]any{"title": title, "version": version},
+		"paths":   d.paths,
+		"components": map[string]any{
+			"schemas":         d.schemas,
+			"securitySchemes": d.secs,
+		},
+	}
+	return d
+}
+
+func (d *openAPIDoc) addModule(module string, res *scanResult) {
+	b := newSchemaBuilder(module, res.Types, "#/components/schemas/", d.schemas)
+
+	for _, e := range res.Endpoints {
+		if e.RouteMethod == "" || e.RoutePath == "" {
+			continue
+		}
+		path, pathParams := openAPIPath(joinRoute(e.Prefix, e.RoutePath))
+		opID := bizTagOf(module, e.MethodName)
+
+		method := strings.ToUpper(e.RouteMethod)
+		if method != "ANY" {
+			d.addOperation(b, e, method, path, pathParams, opID)
+			continue
+		}
+		// ANY 在文档中展开为常用方法，operationId 加方法后缀以保持唯一。
+		for _, m := range anyMethods {
+			d.addOperation(b, e, m, path, pathParams, opID+"."+strings.ToLower(m))
+		}
+	}
+}
+
+// anyMethods 是 @Route ANY 在文档中展开的方法。
+var anyMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+func (d *openAPIDoc) addOperation(b *schemaBuilder, e endpointInfo, method, path string, pathParams []string, opID string) {
+	status, success := successResponse(b, e)
+	op := map[string]any{
+		"operationId": opID,
+		"responses": map[string]any{
+			status: success,
+			"default": map[string]any{
+				"description": "Error",
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": map[string]any{"$ref": "#/components/schemas/Error"},
+					},
+				},
+			},
+		},
+	}
+	if rl := e.RateLimit; rl != nil {
+		op["responses"].(map[string]any)["429"] = errorResponse("Too Many Requests")
+		op["x-rate-limit"] = map[string]any{
+			"limit":  rl.Limit,
+			"period": strings.ToLower(strings.TrimPrefix(rl.Period, "time.")),
+			"burst":  rl.Burst,
+			"key":    rl.Key,
+		}
+	}
+	if e.Timeout > 0 {
+		op["responses"].(map[string]any)["504"] = errorResponse("Timed out after " + e.Timeout.String())
+		op["x-timeout"] = e.Timeout.String()
+	}
+	if e.CircuitBreaker != nil {
+		op["responses"].(map[string]any)["503"] = errorResponse("Circuit breaker open")
+	}
+	if e.Upload > 0 {
+		op["responses"].(map[string]any)["413"] = errorResponse("Upload exceeds " + strconv.FormatInt(e.Upload, 10) + " bytes")
+	}
+	if e.Desc != "" {
+		op["summary"] = e.Desc
+	}
+	if e.Doc != "" {
+		op["description"] = e.Doc
+	}
+	if len(e.Tags) > 0 {
+		op["tags"] = e.Tags
+		for _, t := range e.Tags {
+			d.tags[t] = true
+		}
+	}
+	if e.Auth != "" && e.Auth != "none" {
+		op["security"] = []any{map[string]any{e.Auth: []string{}}}
+		d.secs[e.Auth] = map[string]any{
+			"type":        "http",
+			"scheme":      "bearer",
+			"description": "@Auth " + e.Auth,
+		}
+	}
+
+	params, body := requestParts(b, e, method, pathParams)
+	if e.Idempotent > 0 {
+		params = append(params, map[string]any{
+			"name":        "Idempotency-Key",
+			"in":          "header",
+			"required":    false,
+			"description": "Retries with the same key replay the first response for " + e.Idempotent.String(),
+			"schema":      map[string]any{"type": "string", "maxLength": 255},
+		})
+		op["responses"].(map[string]any)["409"] = errorResponse("A request with the same Idempotency-Key is in progress")
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if body != nil {
+		op["requestBody"] = body
+	}
+
+	item, _ := d.paths[path].(map[string]any)
+	if item == nil {
+		item = map[string]any{}
+		d.paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// errorResponse 描述一个使用 Error 结构的错误响应。
+func errorResponse(desc string) map[string]any {
+	return map[string]any{
+		"description": desc,
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{"$ref": "#/components/schemas/Error"},
+			},
+		},
+	}
+}
+
+// joinRoute 拼接 @Prefix 与路由路径，规则与 biz.Router.Group 一致：结果总以 "/" 开头。
+func joinRoute(prefix, path string) string {
+	full := strings.TrimSuffix(prefix, "/")
+	if path != "" && path != "/" {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		full += path
+	}
+	if !strings.HasPrefix(full, "/") {
+		full = "/" + full
+	}
+	return full
+}
+
+// finish 写入文档级的 tags 列表。
+func (d *openAPIDoc) finish() {
+	names := make([]string, 0, len(d.tags))
+	for t := range d.tags {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+
+	tags := make([]any, 0, len(names))
+	for _, t := range names {
+		tags = append(tags, map[string]any{"name": t})
+	}
+	if len(tags) > 0 {
+		d.root["tags"] = tags
+	}
+}
+
+// successResponse 返回成功响应的状态码与描述：
+// biz 原始响应类型与非 JSON 的 @Produces 不使用 {"code","data"} 包装。
+func successResponse(b *schemaBuilder, e endpointInfo) (string, map[string]any) {
+	binary := map[string]any{"type": "string", "format": "binary"}
+	switch rawResponseKind(e.RespExpr, e.Imports) {
+	case "Redirect":
+		return "302", map[string]any{
+			"description": "Redirect",
+			"headers": map[string]any{
+				"Location": map[string]any{"schema": map[string]any{"type": "string", "format": "uri"}},
+			},
+		}
+	case "Stream":
+		return "200", map[string]any{
+			"description": "Server-sent events",
+			"content": map[string]any{
+				"text/event-stream": map[string]any{"schema": map[string]any{"type": "string"}},
+			},
+		}
+	case "FileResponse":
+		ct := e.Produces
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		return "200", map[string]any{
+			"description": "File",
+			"content":     map[string]any{ct: map[string]any{"schema": binary}},
+		}
+	}
+	if e.Produces != "" && !isJSONMediaType(e.Produces) {
+		return "200", map[string]any{
+			"description": "OK",
+			"content":     map[string]any{e.Produces: map[string]any{"schema": binary}},
+		}
+	}
+	return "200", map[string]any{
+		"description": "OK",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": successEnvelope(b, e)},
+		},
+	}
+}
+
+// successEnvelope 描述 {"code":"OK","data":<Resp>} 响应体。
+func successEnvelope(b *schemaBuilder, e endpointInfo) map[string]any {
+	data := map[string]any{}
+	if e.RespExpr != nil {
+		data = b.schemaOf(e.RespExpr, e.Imports)
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"code": map[string]any{"type": "string", "const": "OK"},
+			"data": data,
+		},
+		"required": []string{"code"},
+	}
+}
+
+// requestParts 将 Req 字段拆分为 path/query/header/cookie 参数与请求体。
+func requestParts(b *schemaBuilder, e endpointInfo, method string, pathParams []string) ([]any, map[string]any) {
+	var params []any
+	declared := map[string]bool{}
+	bodyProps, formProps := map[string]any{}, map[string]any{}
+	var bodyRequired, formRequired []string
+	multipart := e.Upload > 0
+
+	if st, imports := b.requestStruct(e); st != nil {
+		for _, f := range b.fields(st, imports) {
+			switch f.In {
+			case "path", "query", "header", "cookie":
+				p := map[string]any{
+					"name":     f.Param,
+					"in":       f.In,
+					"required": f.Required,
+				}
+				schema := copySchema(f.Schema)
+				if desc, ok := schema["description"]; ok {
+					p["description"] = desc
+					delete(schema, "description")
+				}
+				p["schema"] = schema
+				params = append(params, p)
+				if f.In == "path" {
+					declared[f.Param] = true
+				}
+			case "form":
+				multipart = multipart || f.File
+				formProps[f.Param] = f.Schema
+				if f.Required {
+					formRequired = append(formRequired, f.Param)
+				}
+			default:
+				if f.JSONName == "" {
+					continue
+				}
+				bodyProps[f.JSONName] = f.Schema
+				if f.Required {
+					bodyRequired = append(bodyRequired, f.JSONName)
+				}
+			}
+		}
+	}
+
+	// 路由中出现但 Req 没有声明的路径参数也必须出现在文档里。
+	for _, p := range pathParams {
+		if !declared[p] {
+			params = append(params, map[string]any{
+				"name":     p,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+	}
+
+	if !methodHasBody(method) {
+		return params, nil
+	}
+
+	content := map[string]any{}
+	if len(bodyProps) > 0 {
+		content["application/json"] = map[string]any{"schema": objectSchema(bodyProps, bodyRequired)}
+	}
+	if len(formProps) > 0 {
+		formType := "application/x-www-form-urlencoded"
+		if multipart {
+			formType = "multipart/form-data"
+		}
+		content[formType] = map[string]any{"schema": objectSchema(formProps, formRequired)}
+	}
+	if len(content) == 0 {
+		return params, nil
+	}
+	return params, map[string]any{
+		"required": len(bodyRequired)+len(formRequired) > 0,
+		"content":  content,
+	}
+}
+
+// requestStruct 返回 Req 对应的本包 struct 声明。
+func (b *schemaBuilder) requestStruct(e endpointInfo) (*ast.StructType, map[string]string) {
+	expr := e.ReqExpr
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil, nil
+	}
+	decl, ok := b.types[id.Name]
+	if !ok {
+		return nil, nil
+	}
+	st, ok := decl.Expr.(*ast.StructType)
+	if !ok {
+		return nil, nil
+	}
+	return st, decl.Imports
+}
+
+func objectSchema(props map[string]any, required []string) map[string]any {
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func methodHasBody(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// openAPIPath 将 echo 风格的 /users/:id 转为 /users/{id}，并返回路径参数名。
+func openAPIPath(p string) (string, []string) {
+	segs := strings.Split(p, "/")
+	var params []string
+	for i, s := range segs {
+		switch {
+		case strings.HasPrefix(s, ":"):
+			params = append(params, s[1:])
+			segs[i] = "{" + s[1:] + "}"
+		case s == "*":
+			params = append(params, "path")
+			segs[i] = "{path}"
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
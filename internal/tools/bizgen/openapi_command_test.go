@@ -0,0 +1,22 @@
+package bizgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIGoSource(t *testing.T) {
+	src, err := openAPIGoSource("example.com/shop", "docs", []byte(`{"openapi":"3.1.0"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"package docs\n",
+		`import "example.com/shop/pkg/apidoc"`,
+		`apidoc.SetSpec([]byte("{\"openapi\":\"3.1.0\"}"))`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("source lacks %q:\n%s", want, src)
+		}
+	}
+}
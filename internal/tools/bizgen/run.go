@@ -22,5 +22,8 @@ func Run(ctx context.Context, cfg Config) error {
 	if err := generateRPC(res, cfg.ModuleName); err != nil {
 		return err
 	}
+	if err := generateClient(res, cfg.ModuleName); err != nil {
+		return err
+	}
 	return nil
 }
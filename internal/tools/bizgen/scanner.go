@@ -1,13 +1,19 @@
 package bizgen
 
 import (
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/youbuwei/doeot-go/internal/tools/shared"
+	"github.com/youbuwei/doeot-go/pkg/biz"
 )
 
 // 扫描带注解的 endpoint。
@@ -29,12 +35,17 @@ func scanEndpoints(moduleName string) (*scanResult, error) {
 
 	fset := token.NewFileSet()
 	var eps []endpointInfo
+	typeDecls := map[string]*typeDecl{}
+	prefixes := map[string]string{}
 
 	for _, path := range files {
 		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
 		if err != nil {
 			return nil, err
 		}
+		imports := fileImports(f)
+		collectTypes(f, imports, typeDecls)
+		collectPrefixes(f, prefixes)
 		for _, decl := range f.Decls {
 			fn, ok := decl.(*ast.FuncDecl)
 			if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 {
@@ -55,9 +66,22 @@ func scanEndpoints(moduleName string) (*scanResult, error) {
 			info := endpointInfo{
 				StructName: recvType,
 				MethodName: fn.Name.Name,
+				Imports:    imports,
+			}
+
+			// 签名约定：func (e *X) M(ctx biz.Context, req *MReq) (Resp, error)
+			if params := fn.Type.Params; params != nil && len(params.List) > 0 {
+				last := params.List[len(params.List)-1]
+				info.ReqType = qualifyType(last.Type, imports)
+				info.ReqExpr = last.Type
+			}
+			if results := fn.Type.Results; results != nil && len(results.List) > 0 {
+				info.RespType = qualifyType(results.List[0].Type, imports)
+				info.RespExpr = results.List[0].Type
 			}
 
 			if fn.Doc != nil {
+				info.Doc = docText(fn.Doc, fn.Name.Name)
 				for _, c := range fn.Doc.List {
 					// c.Text 形如 "// @Route  GET /users/:id"
 					text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
@@ -83,6 +107,53 @@ func scanEndpoints(moduleName string) (*scanResult, error) {
 						if len(parts) >= 2 {
 							info.Tags = parts[1:]
 						}
+					case strings.HasPrefix(text, "@Desc"):
+						info.Desc = strings.TrimSpace(strings.TrimPrefix(text, "@Desc"))
+					case strings.HasPrefix(text, "@Upload"):
+						size, err := parseUpload(strings.Fields(text)[1:])
+						if err != nil {
+							return nil, fmt.Errorf("%s: %s: %w", fset.Position(c.Pos()), fn.Name.Name, err)
+						}
+						info.Upload = size
+					case strings.HasPrefix(text, "@Middleware"):
+						// 支持 "@Middleware ratelimit,audit" 与 "@Middleware ratelimit audit"。
+						names := strings.FieldsFunc(strings.TrimPrefix(text, "@Middleware"), func(r rune) bool {
+							return r == ',' || r == ' ' || r == '\t'
+						})
+						info.Middleware = append(info.Middleware, names...)
+					case strings.HasPrefix(text, "@RateLimit"):
+						rl, err := parseRateLimit(strings.Fields(text)[1:])
+						if err != nil {
+							return nil, fmt.Errorf("%s: %s: %w", fset.Position(c.Pos()), fn.Name.Name, err)
+						}
+						info.RateLimit = rl
+					case strings.HasPrefix(text, "@Idempotent"):
+						ttl, err := parseIdempotent(strings.Fields(text)[1:])
+						if err != nil {
+							return nil, fmt.Errorf("%s: %s: %w", fset.Position(c.Pos()), fn.Name.Name, err)
+						}
+						info.Idempotent = ttl
+					case strings.HasPrefix(text, "@Timeout"):
+						parts := strings.Fields(text)
+						if len(parts) < 2 {
+							return nil, fmt.Errorf("%s: %s: @Timeout: missing duration, e.g. 2s", fset.Position(c.Pos()), fn.Name.Name)
+						}
+						d, err := time.ParseDuration(parts[1])
+						if err != nil || d <= 0 {
+							return nil, fmt.Errorf("%s: %s: @Timeout: invalid duration %q", fset.Position(c.Pos()), fn.Name.Name, parts[1])
+						}
+						info.Timeout = d
+					case strings.HasPrefix(text, "@CircuitBreaker"):
+						cb, err := parseCircuitBreaker(strings.Fields(text)[1:])
+						if err != nil {
+							return nil, fmt.Errorf("%s: %s: %w", fset.Position(c.Pos()), fn.Name.Name, err)
+						}
+						info.CircuitBreaker = cb
+					case strings.HasPrefix(text, "@Produces"):
+						parts := strings.Fields(text)
+						if len(parts) >= 2 {
+							info.Produces = parts[1]
+						}
 					}
 				}
 			}
@@ -94,9 +165,308 @@ func scanEndpoints(moduleName string) (*scanResult, error) {
 		}
 	}
 
+	for i := range eps {
+		eps[i].Prefix = prefixes[eps[i].StructName]
+	}
+
 	return &scanResult{
 		Endpoints: eps,
+		Types:     typeDecls,
+		Prefixes:  prefixes,
 		RootDir:   root,
 		ModPath:   modPath,
 	}, nil
 }
+
+// parseUpload 解析 @Upload 的参数，例如 "maxSize=10MB"；省略时使用 biz.DefaultMaxUploadSize。
+func parseUpload(args []string) (int64, error) {
+	size := biz.DefaultMaxUploadSize
+	for _, arg := range args {
+		key, val, ok := strings.Cut(arg, "=")
+		if !ok || key != "maxSize" {
+			return 0, fmt.Errorf("@Upload: unknown argument %q", arg)
+		}
+		n, err := parseSize(val)
+		if err != nil {
+			return 0, fmt.Errorf("@Upload: %w", err)
+		}
+		size = n
+	}
+	return size, nil
+}
+
+// parseSize 解析 10MB / 512KB / 1GB / 1024 这样的大小（1KB = 1024B）。
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper, mult = strings.TrimSuffix(upper, u.suffix), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+// rateLimitPeriods 是 @RateLimit 支持的时间单位及其在生成代码中的写法。
+var rateLimitPeriods = map[string]string{
+	"s": "time.Second", "sec": "time.Second", "second": "time.Second",
+	"m": "time.Minute", "min": "time.Minute", "minute": "time.Minute",
+	"h": "time.Hour", "hour": "time.Hour",
+}
+
+// parseRateLimit 解析 @RateLimit 的参数，例如 "100/s burst=20 key=user"。
+// burst 省略时等于速率，key 省略时为 ip。
+func parseRateLimit(args []string) (*rateLimitInfo, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("@RateLimit: missing rate, e.g. 100/s")
+	}
+	count, unit, ok := strings.Cut(args[0], "/")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n <= 0 {
+		return nil, fmt.Errorf("@RateLimit: invalid rate %q", args[0])
+	}
+	period, ok := rateLimitPeriods[unit]
+	if !ok {
+		return nil, fmt.Errorf("@RateLimit: invalid period %q (use s, m or h)", unit)
+	}
+	rl := &rateLimitInfo{Limit: n, Period: period, Burst: n, Key: "ip"}
+
+	for _, arg := range args[1:] {
+		key, val, _ := strings.Cut(arg, "=")
+		switch key {
+		case "burst":
+			b, err := strconv.Atoi(val)
+			if err != nil || b <= 0 {
+				return nil, fmt.Errorf("@RateLimit: invalid burst %q", val)
+			}
+			rl.Burst = b
+		case "key":
+			switch val {
+			case "ip", "user", "global":
+				rl.Key = val
+			default:
+				return nil, fmt.Errorf("@RateLimit: invalid key %q (use ip, user or global)", val)
+			}
+		default:
+			return nil, fmt.Errorf("@RateLimit: unknown argument %q", arg)
+		}
+	}
+	return rl, nil
+}
+
+// parseIdempotent 解析 @Idempotent 的参数，例如 "ttl=24h"；省略时使用 biz.DefaultIdempotencyTTL。
+func parseIdempotent(args []string) (time.Duration, error) {
+	ttl := biz.DefaultIdempotencyTTL
+	for _, arg := range args {
+		key, val, ok := strings.Cut(arg, "=")
+		if !ok || key != "ttl" {
+			return 0, fmt.Errorf("@Idempotent: unknown argument %q", arg)
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("@Idempotent: invalid ttl %q", val)
+		}
+		ttl = d
+	}
+	return ttl, nil
+}
+
+// parseCircuitBreaker 解析 @CircuitBreaker 的参数，例如
+// "failureRatio=0.5 window=30s minRequests=20 cooldown=10s"，其中 failureRatio 与 window 必填。
+func parseCircuitBreaker(args []string) (*circuitBreakerInfo, error) {
+	cb := &circuitBreakerInfo{}
+	for _, arg := range args {
+		key, val, _ := strings.Cut(arg, "=")
+		var err error
+		switch key {
+		case "failureRatio":
+			cb.FailureRatio, err = strconv.ParseFloat(val, 64)
+			if err == nil && (cb.FailureRatio <= 0 || cb.FailureRatio > 1) {
+				err = fmt.Errorf("must be in (0, 1]")
+			}
+		case "window":
+			cb.Window, err = parsePositiveDuration(val)
+		case "cooldown":
+			cb.Cooldown, err = parsePositiveDuration(val)
+		case "minRequests":
+			cb.MinRequests, err = strconv.Atoi(val)
+			if err == nil && cb.MinRequests <= 0 {
+				err = fmt.Errorf("must be positive")
+			}
+		default:
+			return nil, fmt.Errorf("@CircuitBreaker: unknown argument %q", arg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("@CircuitBreaker: invalid %s %q: %w", key, val, err)
+		}
+	}
+	if cb.FailureRatio == 0 || cb.Window == 0 {
+		return nil, fmt.Errorf("@CircuitBreaker: failureRatio and window are required")
+	}
+	return cb, nil
+}
+
+func parsePositiveDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("must be positive")
+	}
+	return d, err
+}
+
+// collectTypes 收集 endpoint 包中的类型声明（Req/Resp 等），供生成 schema 使用。
+func collectTypes(f *ast.File, imports map[string]string, out map[string]*typeDecl) {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			doc := ts.Doc
+			if doc == nil && len(gd.Specs) == 1 {
+				doc = gd.Doc
+			}
+			out[ts.Name.Name] = &typeDecl{
+				Name:    ts.Name.Name,
+				Doc:     docText(doc, ts.Name.Name),
+				Expr:    ts.Type,
+				Imports: imports,
+			}
+		}
+	}
+}
+
+// collectPrefixes 收集类型声明注释中的 @Prefix，例如：
+//
+//	// UserEndpoint 暴露用户接口。
+//	// @Prefix /api/v1
+//	type UserEndpoint struct{ ... }
+func collectPrefixes(f *ast.File, out map[string]string) {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			doc := ts.Doc
+			if doc == nil && len(gd.Specs) == 1 {
+				doc = gd.Doc
+			}
+			if doc == nil {
+				continue
+			}
+			for _, c := range doc.List {
+				text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+				if parts := strings.Fields(text); len(parts) >= 2 && parts[0] == "@Prefix" {
+					out[ts.Name.Name] = parts[1]
+				}
+			}
+		}
+	}
+}
+
+// docText 提取注释中的说明文字，忽略 @ 注解、go: 指令以及仅包含名字的行。
+func docText(cg *ast.CommentGroup, name string) string {
+	if cg == nil {
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(cg.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "@") || strings.HasPrefix(line, "go:") || line == name {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// fileImports 返回文件中 import 的 包名 -> 路径 映射。
+func fileImports(f *ast.File) map[string]string {
+	res := make(map[string]string, len(f.Imports))
+	for _, imp := range f.Imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(p)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		res[name] = p
+	}
+	return res
+}
+
+// qualifyType 将 endpoint 包内的类型表达式改写为可在其他包中使用的形式：
+// 本包类型加 "endpoint." 前缀，外部包类型记录所需 import。
+// 无法表达的类型返回空 Expr。
+func qualifyType(expr ast.Expr, imports map[string]string) typeRef {
+	ref := typeRef{Imports: map[string]string{}}
+	ref.Expr = qualify(expr, imports, ref.Imports)
+	return ref
+}
+
+func qualify(expr ast.Expr, imports, used map[string]string) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if types.Universe.Lookup(t.Name) != nil {
+			return t.Name
+		}
+		return "endpoint." + t.Name
+	case *ast.StarExpr:
+		return wrapType("*", qualify(t.X, imports, used))
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return ""
+		}
+		return wrapType("[]", qualify(t.Elt, imports, used))
+	case *ast.MapType:
+		k, v := qualify(t.Key, imports, used), qualify(t.Value, imports, used)
+		if k == "" || v == "" {
+			return ""
+		}
+		return "map[" + k + "]" + v
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		if !ok {
+			return ""
+		}
+		p, ok := imports[pkg.Name]
+		if !ok {
+			return ""
+		}
+		used[pkg.Name] = p
+		return pkg.Name + "." + t.Sel.Name
+	case *ast.InterfaceType:
+		if t.Methods == nil || len(t.Methods.List) == 0 {
+			return "any"
+		}
+	}
+	return ""
+}
+
+func wrapType(prefix, elem string) string {
+	if elem == "" {
+		return ""
+	}
+	return prefix + elem
+}
@@ -0,0 +1,75 @@
+package bizgen
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+)
+
+func TestParseIdempotent(t *testing.T) {
+	cases := []struct {
+		args []string
+		want time.Duration
+		ok   bool
+	}{
+		{nil, biz.DefaultIdempotencyTTL, true},
+		{[]string{"ttl=2h"}, 2 * time.Hour, true},
+		{[]string{"ttl=0s"}, 0, false},
+		{[]string{"ttl=soon"}, 0, false},
+		{[]string{"window=1h"}, 0, false},
+	}
+	for _, tc := range cases {
+		got, err := parseIdempotent(tc.args)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("parseIdempotent(%q) = %s, %v; want %s, ok=%v", tc.args, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCollectPrefixes(t *testing.T) {
+	src := `package endpoint
+
+// OrderEndpoint 订单接口。
+// @Prefix /api/v1/orders
+type OrderEndpoint struct{}
+
+type (
+	// @Prefix admin
+	AdminEndpoint struct{}
+
+	UserEndpoint struct{}
+)
+`
+	f, err := parser.ParseFile(token.NewFileSet(), "endpoint.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	collectPrefixes(f, got)
+	want := map[string]string{"OrderEndpoint": "/api/v1/orders", "AdminEndpoint": "admin"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prefixes %v, want %v", got, want)
+	}
+}
+
+func TestJoinRoute(t *testing.T) {
+	cases := []struct{ prefix, path, want string }{
+		{"", "", "/"},
+		{"", "/", "/"},
+		{"", "users", "/users"},
+		{"/", "/users", "/users"},
+		{"/api/v1", "", "/api/v1"},
+		{"/api/v1/", "/", "/api/v1"},
+		{"/api/v1", "users/:id", "/api/v1/users/:id"},
+		{"admin", "/users", "/admin/users"},
+	}
+	for _, tc := range cases {
+		if got := joinRoute(tc.prefix, tc.path); got != tc.want {
+			t.Errorf("joinRoute(%q, %q) = %q, want %q", tc.prefix, tc.path, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package bizgen
+
+import (
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// schemaBuilder 根据 endpoint 包中的 Go 类型生成 JSON Schema（OpenAPI 3.1 / OpenRPC 通用）。
+//
+// 本包内的 struct 类型会放入 defs 并以 $ref 引用，refPrefix 决定引用的位置，
+// 例如 "#/components/schemas/"（OpenAPI）或 "#/$defs/"（自包含 schema）。
+type schemaBuilder struct {
+	module    string
+	types     map[string]*typeDecl
+	refPrefix string
+	defs      map[string]any
+}
+
+func newSchemaBuilder(module string, types map[string]*typeDecl, refPrefix string, defs map[string]any) *schemaBuilder {
+	if defs == nil {
+		defs = map[string]any{}
+	}
+	return &schemaBuilder{
+		module:    module,
+		types:     types,
+		refPrefix: refPrefix,
+		defs:      defs,
+	}
+}
+
+// fieldInfo 描述 struct 的一个字段。
+type fieldInfo struct {
+	GoName   string
+	JSONName string // 为空表示 json:"-"
+	In       string // path / query / header / cookie / form；为空表示 JSON body
+	Param    string // In 对应的参数名
+	Required bool
+	File     bool // biz.File / *biz.File / []*biz.File 上传字段
+	Schema   map[string]any
+}
+
+// defName 返回类型在 defs 中的名字，带模块前缀以避免跨模块冲突。
+func (b *schemaBuilder) defName(name string) string {
+	return b.module + "." + name
+}
+
+// schemaOf 返回表达式对应的 schema。
+func (b *schemaBuilder) schemaOf(expr ast.Expr, imports map[string]string) map[string]any {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if s := builtinSchema(t.Name); s != nil {
+			return s
+		}
+		decl, ok := b.types[t.Name]
+		if !ok {
+			return map[string]any{}
+		}
+		if _, isStruct := decl.Expr.(*ast.StructType); !isStruct {
+			s := b.schemaOf(decl.Expr, decl.Imports)
+			if decl.Doc != "" {
+				s["description"] = decl.Doc
+			}
+			return s
+		}
+		name := b.defName(t.Name)
+		if _, done := b.defs[name]; !done {
+			// 先占位，避免递归类型无限展开。
+			b.defs[name] = map[string]any{}
+			s := b.structSchema(decl.Expr.(*ast.StructType), decl.Imports)
+			if decl.Doc != "" {
+				s["description"] = decl.Doc
+			}
+			b.defs[name] = s
+		}
+		return map[string]any{"$ref": b.refPrefix + name}
+	case *ast.StarExpr:
+		return b.schemaOf(t.X, imports)
+	case *ast.ArrayType:
+		if id, ok := t.Elt.(*ast.Ident); ok && (id.Name == "byte" || id.Name == "uint8") {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": b.schemaOf(t.Elt, imports)}
+	case *ast.MapType:
+		return map[string]any{"type": "object", "additionalProperties": b.schemaOf(t.Value, imports)}
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok {
+			return externalSchema(imports[pkg.Name], t.Sel.Name)
+		}
+	case *ast.StructType:
+		return b.structSchema(t, imports)
+	}
+	return map[string]any{}
+}
+
+// structSchema 生成对象 schema，包含所有 JSON 可见字段。
+func (b *schemaBuilder) structSchema(st *ast.StructType, imports map[string]string) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for _, f := range b.fields(st, imports) {
+		if f.JSONName == "" {
+			continue
+		}
+		props[f.JSONName] = f.Schema
+		if f.Required {
+			required = append(required, f.JSONName)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// fields 展开 struct 字段（含内嵌的本包 struct）。
+func (b *schemaBuilder) fields(st *ast.StructType, imports map[string]string) []fieldInfo {
+	var res []fieldInfo
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			if raw, err := strconv.Unquote(f.Tag.Value); err == nil {
+				tag = reflect.StructTag(raw)
+			}
+		}
+
+		if len(f.Names) == 0 {
+			// 内嵌字段：本包 struct 直接展开。
+			expr := f.Type
+			if star, ok := expr.(*ast.StarExpr); ok {
+				expr = star.X
+			}
+			if id, ok := expr.(*ast.Ident); ok {
+				if decl, ok := b.types[id.Name]; ok {
+					if inner, ok := decl.Expr.(*ast.StructType); ok && tag.Get("json") == "" {
+						res = append(res, b.fields(inner, decl.Imports)...)
+					}
+				}
+			}
+			continue
+		}
+
+		desc := strings.TrimSpace(f.Doc.Text())
+		if desc == "" {
+			desc = strings.TrimSpace(f.Comment.Text())
+		}
+
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			fi := fieldInfo{GoName: name.Name, JSONName: name.Name}
+			if j := tag.Get("json"); j != "" {
+				jn, _, _ := strings.Cut(j, ",")
+				switch jn {
+				case "-":
+					fi.JSONName = ""
+				case "":
+				default:
+					fi.JSONName = jn
+				}
+			}
+			for _, in := range []string{"path", "query", "header", "cookie", "form"} {
+				if v := tag.Get(in); v != "" {
+					fi.In, fi.Param = in, v
+					break
+				}
+			}
+
+			fi.File = isFileExpr(f.Type, imports)
+			fi.Schema = copySchema(b.schemaOf(f.Type, imports))
+			fi.Required = applyValidate(fi.Schema, tag.Get("validate"))
+			if fi.In == "path" {
+				fi.Required = true
+			}
+			if desc != "" {
+				fi.Schema["description"] = desc
+			}
+			res = append(res, fi)
+		}
+	}
+	return res
+}
+
+// isFileExpr 判断字段类型是否为 biz.File（允许指针与切片）。
+func isFileExpr(expr ast.Expr, imports map[string]string) bool {
+	if arr, ok := expr.(*ast.ArrayType); ok {
+		expr = arr.Elt
+	}
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && sel.Sel.Name == "File" && strings.HasSuffix(imports[pkg.Name], "/pkg/biz")
+}
+
+// rawResponseKind 返回响应类型对应的 biz 原始响应类型名
+// （FileResponse / Redirect / Stream，允许指针），其他类型返回空串。
+func rawResponseKind(expr ast.Expr, imports map[string]string) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || !strings.HasSuffix(imports[pkg.Name], "/pkg/biz") {
+		return ""
+	}
+	switch sel.Sel.Name {
+	case "FileResponse", "Redirect", "Stream":
+		return sel.Sel.Name
+	}
+	return ""
+}
+
+// isJSONMediaType 判断 Content-Type 是否为 JSON（application/json 或 +json）。
+func isJSONMediaType(ct string) bool {
+	mt, _, _ := strings.Cut(ct, ";")
+	mt = strings.TrimSpace(mt)
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// copySchema 复制顶层 map，避免修改共享的 $ref / 内置 schema。
+func copySchema(s map[string]any) map[string]any {
+	c := make(map[string]any, len(s))
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
+
+// applyValidate 将 validate 标签转换为 schema 约束，返回字段是否必填。
+func applyValidate(s map[string]any, rules string) bool {
+	if rules == "" {
+		return false
+	}
+	typ, _ := s["type"].(string)
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "dive":
+			// 之后的规则作用于元素，这里不再展开。
+			return required
+		case "required":
+			required = true
+		case "min", "max", "len":
+			applyBound(s, typ, name, arg)
+		case "gte":
+			applyBound(s, typ, "min", arg)
+		case "lte":
+			applyBound(s, typ, "max", arg)
+		case "gt":
+			if n, ok := parseNumber(arg); ok && isNumeric(typ) {
+				s["exclusiveMinimum"] = n
+			}
+		case "lt":
+			if n, ok := parseNumber(arg); ok && isNumeric(typ) {
+				s["exclusiveMaximum"] = n
+			}
+		case "oneof":
+			var enum []any
+			for _, v := range strings.Fields(arg) {
+				if n, ok := parseNumber(v); ok && isNumeric(typ) {
+					enum = append(enum, n)
+				} else {
+					enum = append(enum, v)
+				}
+			}
+			s["enum"] = enum
+		case "email":
+			s["format"] = "email"
+		case "url", "uri", "http_url":
+			s["format"] = "uri"
+		case "uuid", "uuid4":
+			s["format"] = "uuid"
+		case "ip", "ipv4":
+			s["format"] = "ipv4"
+		case "ipv6":
+			s["format"] = "ipv6"
+		case "datetime":
+			s["format"] = "date-time"
+		}
+	}
+	return required
+}
+
+// applyBound 按类型把 min/max/len 映射为长度、数值或元素个数约束。
+func applyBound(s map[string]any, typ, kind, arg string) {
+	n, ok := parseNumber(arg)
+	if !ok {
+		return
+	}
+	var lo, hi string
+	switch {
+	case typ == "string":
+		lo, hi = "minLength", "maxLength"
+	case typ == "array":
+		lo, hi = "minItems", "maxItems"
+	case typ == "object":
+		lo, hi = "minProperties", "maxProperties"
+	case isNumeric(typ):
+		lo, hi = "minimum", "maximum"
+	default:
+		return
+	}
+	switch kind {
+	case "min":
+		s[lo] = n
+	case "max":
+		s[hi] = n
+	case "len":
+		if isNumeric(typ) {
+			s["const"] = n
+			return
+		}
+		s[lo], s[hi] = n, n
+	}
+}
+
+func isNumeric(typ string) bool {
+	return typ == "integer" || typ == "number"
+}
+
+func parseNumber(s string) (any, bool) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// builtinSchema 返回 Go 内置类型对应的 schema，非内置类型返回 nil。
+func builtinSchema(name string) map[string]any {
+	switch name {
+	case "string":
+		return map[string]any{"type": "string"}
+	case "bool":
+		return map[string]any{"type": "boolean"}
+	case "int8", "int16", "int32", "uint8", "uint16", "rune", "byte":
+		return map[string]any{"type": "integer", "format": "int32"}
+	case "int", "uint", "int64", "uint32", "uint64":
+		// int / uint 在支持的平台上都是 64 位；uint32 超出 int32 的范围。
+		return map[string]any{"type": "integer", "format": "int64"}
+	case "float32":
+		return map[string]any{"type": "number", "format": "float"}
+	case "float64":
+		return map[string]any{"type": "number", "format": "double"}
+	case "any":
+		return map[string]any{}
+	}
+	return nil
+}
+
+// externalSchema 返回常见外部类型对应的 schema。
+func externalSchema(pkgPath, name string) map[string]any {
+	switch pkgPath + "." + name {
+	case "time.Time":
+		return map[string]any{"type": "string", "format": "date-time"}
+	case "time.Duration":
+		return map[string]any{"type": "integer", "format": "int64"}
+	case "encoding/json.RawMessage":
+		return map[string]any{}
+	}
+	if name == "File" && strings.HasSuffix(pkgPath, "/pkg/biz") {
+		return map[string]any{"type": "string", "format": "binary"}
+	}
+	return map[string]any{"type": "object"}
+}
@@ -0,0 +1,58 @@
+package bizgen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestBuiltinSchemaIntegers(t *testing.T) {
+	cases := map[string]string{
+		"int":    "int64",
+		"uint":   "int64",
+		"int64":  "int64",
+		"uint64": "int64",
+		"uint32": "int64",
+		"int32":  "int32",
+		"int16":  "int32",
+		"uint16": "int32",
+		"rune":   "int32",
+	}
+	for name, format := range cases {
+		want := map[string]any{"type": "integer", "format": format}
+		if got := builtinSchema(name); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: schema %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestStructSchema(t *testing.T) {
+	src := `package user
+
+type CreateUserResp struct {
+	ID    int64  ` + "`json:\"id\"`" + `
+	Name  string ` + "`json:\"name\"`" + `
+	Age   int    ` + "`json:\"age\"`" + `
+	Score uint16 ` + "`json:\"score\"`" + `
+}
+`
+	f, err := parser.ParseFile(token.NewFileSet(), "resp.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType)
+
+	b := newSchemaBuilder("user", nil, "#/$defs/", nil)
+	props := b.structSchema(st, nil)["properties"].(map[string]any)
+	want := map[string]any{
+		"id":    map[string]any{"type": "integer", "format": "int64"},
+		"name":  map[string]any{"type": "string"},
+		"age":   map[string]any{"type": "integer", "format": "int64"},
+		"score": map[string]any{"type": "integer", "format": "int32"},
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("properties %v, want %v", props, want)
+	}
+}
@@ -9,8 +9,9 @@ import (
 var templatesFS embed.FS
 
 var (
-	httpTmpl *template.Template
-	rpcTmpl  *template.Template
+	httpTmpl   *template.Template
+	rpcTmpl    *template.Template
+	clientTmpl *template.Template
 )
 
 func init() {
@@ -23,4 +24,8 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	clientTmpl, err = template.ParseFS(templatesFS, "templates/client.tmpl")
+	if err != nil {
+		panic(err)
+	}
 }
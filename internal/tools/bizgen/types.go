@@ -1,21 +1,74 @@
 package bizgen
 
+import (
+	"go/ast"
+	"time"
+)
+
 // 从 endpoint 源码扫描出来的基础信息。
 type endpointInfo struct {
-	StructName  string   // 如 UserEndpoint / OrderEndpoint
-	MethodName  string   // 如 GetUser
-	RouteMethod string   // "GET"/"POST"/...
-	RoutePath   string   // "/users/:id"
-	RPCMethod   string   // "User.Get"
-	Auth        string   // 来自 @Auth
-	Tags        []string // 来自 @Tags
+	StructName     string              // 如 UserEndpoint / OrderEndpoint
+	MethodName     string              // 如 GetUser
+	RouteMethod    string              // "GET"/"POST"/...
+	RoutePath      string              // "/users/:id"
+	RPCMethod      string              // "User.Get"
+	Auth           string              // 来自 @Auth
+	Tags           []string            // 来自 @Tags
+	Desc           string              // 来自 @Desc
+	Doc            string              // 方法的 Go doc 注释（去掉注解行）
+	Upload         int64               // 来自 @Upload：请求体大小上限（字节），0 表示不是上传接口
+	Produces       string              // 来自 @Produces：响应 Content-Type
+	Prefix         string              // 所属 endpoint 结构体的 @Prefix
+	Middleware     []string            // 来自 @Middleware，按声明顺序（第一个在最外层）
+	RateLimit      *rateLimitInfo      // 来自 @RateLimit，nil 表示不限流
+	Idempotent     time.Duration       // 来自 @Idempotent：幂等键保留时长，0 表示非幂等接口
+	Timeout        time.Duration       // 来自 @Timeout，0 表示不设置超时
+	CircuitBreaker *circuitBreakerInfo // 来自 @CircuitBreaker，nil 表示不熔断
+	ReqType        typeRef             // 最后一个参数类型，如 *endpoint.GetUserReq
+	RespType       typeRef             // 第一个返回值类型，如 *endpoint.GetUserResp
+
+	ReqExpr  ast.Expr          // 请求参数类型的 AST，用于生成 schema
+	RespExpr ast.Expr          // 返回值类型的 AST
+	Imports  map[string]string // 方法所在文件的 import：包名 -> 路径
+}
+
+// rateLimitInfo 是解析后的 @RateLimit。
+type rateLimitInfo struct {
+	Limit  int
+	Period string // 生成代码中的周期表达式，如 "time.Second"
+	Burst  int
+	Key    string // ip / user / global
+}
+
+// circuitBreakerInfo 是解析后的 @CircuitBreaker，零值字段使用运行时默认值。
+type circuitBreakerInfo struct {
+	FailureRatio float64
+	Window       time.Duration
+	MinRequests  int
+	Cooldown     time.Duration
+}
+
+// typeRef 是一个可在生成代码中直接书写的类型表达式。
+type typeRef struct {
+	Expr    string            // 如 "*endpoint.GetUserResp" / "[]*endpoint.GetUserResp"
+	Imports map[string]string // 表达式用到的外部包：包名 -> import path
+}
+
+// typeDecl 是 endpoint 包中的一个类型声明。
+type typeDecl struct {
+	Name    string
+	Doc     string
+	Expr    ast.Expr
+	Imports map[string]string // 声明所在文件的 import：包名 -> 路径
 }
 
 // 扫描结果：包含模块根目录等信息。
 type scanResult struct {
 	Endpoints []endpointInfo
-	RootDir   string // 仓库根目录（包含 go.mod）
-	ModPath   string // go.mod 里的 module 路径
+	Types     map[string]*typeDecl // endpoint 包内的类型声明
+	Prefixes  map[string]string    // endpoint 结构体名 -> @Prefix
+	RootDir   string               // 仓库根目录（包含 go.mod）
+	ModPath   string               // go.mod 里的 module 路径
 }
 
 // 模板使用的结构（HTTP）。
@@ -30,6 +83,8 @@ type httpTemplateData struct {
 	ModPath      string
 	Module       string
 	EndpointType string
+	Prefix       string // 来自 endpoint 结构体上的 @Prefix
+	ImportTime   bool   // Options 中用到了 time 包（@RateLimit）
 	Endpoints    []httpEndpointData
 }
 
@@ -44,5 +99,22 @@ type rpcTemplateData struct {
 	ModPath      string
 	Module       string
 	EndpointType string
+	ImportTime   bool
 	Endpoints    []rpcEndpointData
 }
+
+// 模板使用的结构（RPC client）。
+type clientMethodData struct {
+	MethodName string
+	RPCMethod  string
+	ReqType    string // "*endpoint.GetUserReq"
+	RespType   string // "*endpoint.GetUserResp"
+	RespElem   string // RespType 为指针时的元素类型，用于声明接收变量
+}
+
+type clientTemplateData struct {
+	ModPath string
+	Module  string
+	Imports []string // 额外 import 行（可能带别名），已排序
+	Methods []clientMethodData
+}
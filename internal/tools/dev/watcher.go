@@ -63,7 +63,7 @@ func needsGenerate(path string) bool {
 	return false
 }
 
-// 判断是否是 bizgen 生成的 HTTP/RPC wrapper。
+// 判断是否是 bizgen 生成的 HTTP/RPC wrapper、RPC client 或嵌入的 OpenAPI 文档。
 func isGeneratedWrapper(path string) bool {
 	if strings.Contains(path, "/interfaces/http/") && strings.Contains(path, "zz_") {
 		return true
@@ -71,6 +71,12 @@ func isGeneratedWrapper(path string) bool {
 	if strings.Contains(path, "/interfaces/rpc/") && strings.Contains(path, "zz_") {
 		return true
 	}
+	if strings.Contains(path, "/interfaces/rpcclient/") && strings.Contains(path, "zz_") {
+		return true
+	}
+	if strings.HasSuffix(path, "zz_openapi_gen.go") {
+		return true
+	}
 	return false
 }
 
@@ -36,7 +36,7 @@ type CreateUserReq struct {
 	Name  string `json:"name" validate:"required,min=3"`
 	Age   int    `json:"age" validate:"gte=0,lte=120"`
 	Role  string `json:"role" validate:"omitempty,oneof=normal admin"`
-	Phone string `json:"phone" validate:"required,mobile"`
+	Phone string `json:"phone" validate:"required,mobile,unique_db=users.phone"`
 }
 
 // Validate implements validate.Custom for CreateUserReq.
@@ -73,7 +73,7 @@ type UserEndpoint struct {
 // @Tags   user
 func (e *UserEndpoint) GetUser(ctx biz.Context, req *GetUserReq) (*GetUserResp, error) {
 	// By the time we arrive here, basic request validation (gt=0) is already
-	// performed by the generated wrapper using validate.Struct.
+	// performed by the generated wrapper using validate.StructCtx.
 	u, err := e.Svc.GetUser(ctx.RequestContext(), req.ID)
 	if errors.Is(err, domain.ErrUserNotFound) {
 		return nil, errs.NotFound("user not found")
@@ -0,0 +1,40 @@
+// Package apidoc holds the OpenAPI document embedded into the binary and the
+// self-contained HTML page boot.App serves it with.
+//
+// The spec is registered by a file generated with
+// `doeot openapi -go <path>` (see internal/modules), so it is fixed at build
+// time and always matches the compiled endpoints.
+package apidoc
+
+import (
+	_ "embed"
+	"sync"
+)
+
+//go:embed docs.html
+var docsHTML []byte
+
+var (
+	mu   sync.RWMutex
+	spec []byte
+)
+
+// SetSpec registers the OpenAPI JSON document. Generated code calls it from init.
+func SetSpec(b []byte) {
+	mu.Lock()
+	defer mu.Unlock()
+	spec = b
+}
+
+// Spec returns the registered OpenAPI document, or nil if none was generated.
+func Spec() []byte {
+	mu.RLock()
+	defer mu.RUnlock()
+	return spec
+}
+
+// DocsHTML returns the interactive docs page. It loads the spec from
+// the relative URL "openapi.json" and needs no external assets.
+func DocsHTML() []byte {
+	return docsHTML
+}
@@ -0,0 +1,46 @@
+package biz
+
+// Well-known auth schemes used with @Auth / WithAuth.
+const (
+	// AuthNone marks a route as public; no Authenticator is consulted.
+	AuthNone = "none"
+)
+
+// Principal describes the authenticated caller of a request.
+type Principal struct {
+	ID    string
+	Name  string
+	Roles []string
+	Attrs map[string]any
+}
+
+// HasRole reports whether the principal carries the given role.
+func (p *Principal) HasRole(role string) bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator resolves the caller of a route protected by an auth scheme
+// (login, admin, api-key, ...). Implementations read credentials from ctx
+// (e.g. ctx.Header("Authorization")).
+//
+// Returning an *errs.Error lets the authenticator choose between
+// errs.Unauthorized and errs.Forbidden; any other error is reported as
+// unauthorized.
+type Authenticator interface {
+	Authenticate(ctx Context, meta *RouteMeta) (*Principal, error)
+}
+
+// AuthenticatorFunc adapts a plain function to Authenticator.
+type AuthenticatorFunc func(ctx Context, meta *RouteMeta) (*Principal, error)
+
+func (f AuthenticatorFunc) Authenticate(ctx Context, meta *RouteMeta) (*Principal, error) {
+	return f(ctx, meta)
+}
@@ -3,6 +3,8 @@ package biz
 import (
     "context"
     "encoding/json"
+    "net/http"
+    "time"
 )
 
 // Context is the abstraction exposed to business handlers.
@@ -11,6 +13,24 @@ type Context interface {
     RequestContext() context.Context
     RequestID() string
 
+    // Header returns a request header (HTTP header or RPC transport header).
+    Header(key string) string
+    // Headers returns a copy of all request headers / RPC metadata.
+    Headers() http.Header
+    // Cookie returns the value of the named request cookie.
+    Cookie(name string) (string, bool)
+    // RemoteAddr returns the client IP address.
+    RemoteAddr() string
+    // Route returns the metadata of the matched route or RPC method.
+    Route() *RouteMeta
+    // Principal returns the caller resolved by the route's Authenticator,
+    // or nil for public routes.
+    Principal() *Principal
+
+    // Get / Set store request-scoped values, e.g. for middleware.
+    Get(key string) any
+    Set(key string, val any)
+
     Bind(out any) error
     JSON(status int, body any) error
     Result(data any, err error) error
@@ -19,12 +39,30 @@ type Context interface {
 // HandlerFunc is the function signature used by HTTP handlers.
 type HandlerFunc func(Context) error
 
+// Middleware wraps a handler. It is transport-neutral: the same chain runs
+// for an endpoint's HTTP route and its RPC method (where the RPC handler is
+// adapted to a HandlerFunc whose outcome is reported through ctx.Result).
+// A middleware short-circuits by returning ctx.Result(nil, err). One that
+// passes a wrapping Context down the chain should expose the original via
+// an Unwrap() Context method.
+type Middleware func(HandlerFunc) HandlerFunc
+
 // Router is an abstract HTTP router used by modules to register routes.
 type Router interface {
     GET(path string, h HandlerFunc, opts ...RouteOption)
     POST(path string, h HandlerFunc, opts ...RouteOption)
     PUT(path string, h HandlerFunc, opts ...RouteOption)
     DELETE(path string, h HandlerFunc, opts ...RouteOption)
+    PATCH(path string, h HandlerFunc, opts ...RouteOption)
+    HEAD(path string, h HandlerFunc, opts ...RouteOption)
+    OPTIONS(path string, h HandlerFunc, opts ...RouteOption)
+    // ANY registers h for every HTTP method.
+    ANY(path string, h HandlerFunc, opts ...RouteOption)
+
+    // Group returns a Router mounting routes under prefix. Its opts are
+    // applied before each route's own options, so auth, tags, etc. are
+    // inherited and can be overridden per route.
+    Group(prefix string, opts ...RouteOption) Router
 }
 
 // RPCHandlerFunc is the function signature used by RPC handlers.
@@ -40,6 +78,29 @@ type RouteMeta struct {
     Auth   string
     Tags   []string
     BizTag string
+    Desc   string
+
+    // MaxUploadSize > 0 marks a multipart upload route and bounds its body.
+    MaxUploadSize int64
+    // Produces is the response content type set by @Produces.
+    Produces string
+    // Middleware lists middleware names (@Middleware), outermost first.
+    Middleware []string
+    // RateLimit throttles callers of the route (@RateLimit); nil means unlimited.
+    RateLimit *RateLimit
+    // IdempotencyTTL > 0 makes the route replay the first response to calls
+    // repeating an Idempotency-Key (@Idempotent) for that long.
+    IdempotencyTTL time.Duration
+    // Timeout > 0 sets a deadline on RequestContext (@Timeout).
+    Timeout time.Duration
+    // CircuitBreaker fails calls fast while the BizTag's breaker is open
+    // (@CircuitBreaker); nil means no breaker.
+    CircuitBreaker *CircuitBreaker
+
+    // ParamsSchema / ResultSchema are self-contained JSON Schemas of the
+    // Req/Resp types generated by bizgen (nested types live in "$defs").
+    ParamsSchema json.RawMessage
+    ResultSchema json.RawMessage
 }
 
 // RouteOption mutates RouteMeta.
@@ -63,9 +124,47 @@ func WithBizTag(tag string) RouteOption {
     }
 }
 
+// WithMiddleware appends named middleware registered on boot.App.
+func WithMiddleware(names ...string) RouteOption {
+    return func(m *RouteMeta) {
+        m.Middleware = append(m.Middleware, names...)
+    }
+}
+
+func WithDesc(desc string) RouteOption {
+    return func(m *RouteMeta) {
+        m.Desc = desc
+    }
+}
+
+// WithSchema attaches the JSON Schemas of the params and result.
+// An empty string leaves the corresponding schema unset.
+func WithSchema(params, result string) RouteOption {
+    return func(m *RouteMeta) {
+        if params != "" {
+            m.ParamsSchema = json.RawMessage(params)
+        }
+        if result != "" {
+            m.ResultSchema = json.RawMessage(result)
+        }
+    }
+}
+
 // Module is implemented by each business module (user/order/...).
 type Module interface {
     Name() string
     RegisterHTTP(r Router)
     RegisterRPC(r RPCRouter)
 }
+
+// Starter can be implemented by a Module to run logic (e.g. start background
+// workers) before the transports begin serving. Returning an error aborts Run.
+type Starter interface {
+    OnStart(ctx context.Context) error
+}
+
+// Stopper can be implemented by a Module to release resources (e.g. flush
+// workers) after the transports have drained. ctx carries the drain deadline.
+type Stopper interface {
+    OnStop(ctx context.Context) error
+}
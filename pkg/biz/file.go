@@ -0,0 +1,42 @@
+package biz
+
+import "io"
+
+// DefaultMaxUploadSize is the request size limit of @Upload routes that do
+// not set maxSize.
+const DefaultMaxUploadSize int64 = 32 << 20
+
+// File is an uploaded file. Req structs hold it with a form tag:
+//
+//	Avatar *biz.File `form:"avatar"`
+//
+// Use []*biz.File for repeated parts. The content is read through Open,
+// so large files are never loaded into the Req struct itself.
+type File struct {
+	Name        string
+	Size        int64
+	ContentType string
+
+	open func() (io.ReadCloser, error)
+}
+
+// NewFile creates a File whose content is provided by open.
+func NewFile(name string, size int64, contentType string, open func() (io.ReadCloser, error)) *File {
+	return &File{Name: name, Size: size, ContentType: contentType, open: open}
+}
+
+// Open returns the file content. Callers must close it.
+func (f *File) Open() (io.ReadCloser, error) {
+	return f.open()
+}
+
+// WithUpload marks a route as accepting multipart uploads and limits the
+// request body to maxSize bytes (DefaultMaxUploadSize if maxSize <= 0).
+func WithUpload(maxSize int64) RouteOption {
+	return func(m *RouteMeta) {
+		if maxSize <= 0 {
+			maxSize = DefaultMaxUploadSize
+		}
+		m.MaxUploadSize = maxSize
+	}
+}
@@ -0,0 +1,23 @@
+package biz
+
+import "time"
+
+// HeaderIdempotencyKey carries the client-chosen key of an @Idempotent call,
+// over HTTP and as JSON-RPC transport metadata alike.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// DefaultIdempotencyTTL is how long keys are remembered when @Idempotent
+// has no ttl argument.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// WithIdempotency makes a route idempotent (@Idempotent): calls repeating an
+// Idempotency-Key within ttl get the first call's response replayed.
+// ttl <= 0 defaults to DefaultIdempotencyTTL.
+func WithIdempotency(ttl time.Duration) RouteOption {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return func(m *RouteMeta) {
+		m.IdempotencyTTL = ttl
+	}
+}
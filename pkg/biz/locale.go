@@ -0,0 +1,23 @@
+package biz
+
+import "context"
+
+// HeaderAcceptLanguage carries the caller's preferred languages over HTTP and
+// JSON-RPC, e.g. "zh-CN,zh;q=0.9,en;q=0.8".
+const HeaderAcceptLanguage = "Accept-Language"
+
+type localeKey struct{}
+
+// WithLocale returns a copy of ctx carrying the caller's language
+// preference, in Accept-Language syntax.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFrom returns the language preference stored in ctx, or "".
+// validate uses it to pick the language of its messages and outbound
+// clients forward it.
+func LocaleFrom(ctx context.Context) string {
+	l, _ := ctx.Value(localeKey{}).(string)
+	return l
+}
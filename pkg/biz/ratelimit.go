@@ -0,0 +1,37 @@
+package biz
+
+import "time"
+
+// Rate limit keys of @RateLimit / WithRateLimit.
+const (
+	// RateLimitByIP gives every client IP its own budget (the default).
+	RateLimitByIP = "ip"
+	// RateLimitByUser gives every Principal its own budget; anonymous
+	// callers fall back to their IP.
+	RateLimitByUser = "user"
+	// RateLimitGlobal shares one budget between all callers of the route.
+	RateLimitGlobal = "global"
+)
+
+// RateLimit is a token bucket refilled with Limit tokens per Period and
+// holding at most Burst tokens. Every call takes one token.
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+	Key    string
+}
+
+// WithRateLimit throttles a route to limit calls per period (@RateLimit).
+// burst <= 0 defaults to limit and an empty key to RateLimitByIP.
+func WithRateLimit(limit int, period time.Duration, burst int, key string) RouteOption {
+	if burst <= 0 {
+		burst = limit
+	}
+	if key == "" {
+		key = RateLimitByIP
+	}
+	return func(m *RouteMeta) {
+		m.RateLimit = &RateLimit{Limit: limit, Period: period, Burst: burst, Key: key}
+	}
+}
@@ -0,0 +1,53 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// HeaderRequestID is the header used to carry request IDs over HTTP and
+// JSON-RPC (which is transported over HTTP as well).
+const HeaderRequestID = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the request ID stored in ctx, or "".
+// Repositories and outbound clients use it to tag logs and propagate calls.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID generates a random 128-bit hex request ID.
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// MaxRequestIDLen bounds the request IDs accepted from callers.
+const MaxRequestIDLen = 128
+
+// ValidRequestID reports whether a request ID received from a caller may be
+// used as is: 1 to MaxRequestIDLen characters of [A-Za-z0-9._-]. The ID ends
+// up in response headers, logs and downstream calls, so anything else is
+// replaced with NewRequestID.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
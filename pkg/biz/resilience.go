@@ -0,0 +1,35 @@
+package biz
+
+import "time"
+
+// WithTimeout bounds a route (@Timeout): RequestContext() carries a deadline
+// d after the call starts. Handlers must honour it (e.g. by passing the
+// context to DB and RPC calls); errors caused by it are reported as
+// errs.Timeout.
+func WithTimeout(d time.Duration) RouteOption {
+	return func(m *RouteMeta) {
+		m.Timeout = d
+	}
+}
+
+// CircuitBreaker configures the breaker of a route (@CircuitBreaker). The
+// breaker is shared by every route and RPC method with the same BizTag.
+// Zero MinRequests and Cooldown use the breaker package defaults.
+type CircuitBreaker struct {
+	// FailureRatio in (0, 1] of failed calls within Window trips the breaker.
+	FailureRatio float64
+	Window       time.Duration
+	// MinRequests within Window before the ratio is considered.
+	MinRequests int
+	// Cooldown is how long the breaker rejects calls before probing.
+	Cooldown time.Duration
+}
+
+// WithCircuitBreaker fails calls fast with errs.Unavailable while the
+// route's breaker is open. Only server-side failures (INTERNAL, TIMEOUT,
+// UNAVAILABLE and non-errs errors) count as failures.
+func WithCircuitBreaker(cb CircuitBreaker) RouteOption {
+	return func(m *RouteMeta) {
+		m.CircuitBreaker = &cb
+	}
+}
@@ -0,0 +1,55 @@
+package biz
+
+import (
+	"io"
+	"time"
+)
+
+// Raw response types. Endpoints return them (by value or pointer) instead of
+// a Resp struct, and Context.Result writes them as-is instead of wrapping
+// them in the {"code","data"} envelope. They are HTTP-only: bizgen refuses
+// to generate @RPC wrappers for endpoints returning them.
+
+// FileResponse sends Body as a download (or inline content).
+type FileResponse struct {
+	// Name is the file name offered to the client in Content-Disposition.
+	Name string
+	// ContentType defaults to the route's @Produces, then to
+	// application/octet-stream.
+	ContentType string
+	// Size sets Content-Length when > 0.
+	Size int64
+	// Inline displays the file in the browser instead of downloading it.
+	Inline bool
+	// Body is closed after writing if it implements io.Closer.
+	Body io.Reader
+}
+
+// Redirect sends the client to URL. Status defaults to 302 Found.
+type Redirect struct {
+	URL    string
+	Status int
+}
+
+// Event is one server-sent event. Data is written verbatim when it is a
+// string or []byte and JSON-encoded otherwise.
+type Event struct {
+	ID    string
+	Event string
+	Data  any
+	Retry time.Duration
+}
+
+// Stream sends Events as text/event-stream until the channel is closed or
+// the client goes away. Producers should stop when RequestContext is done.
+type Stream struct {
+	Events <-chan Event
+}
+
+// WithProduces sets the response content type of a route (@Produces).
+// Result uses it for FileResponse and for string / []byte / io.Reader data.
+func WithProduces(contentType string) RouteOption {
+	return func(m *RouteMeta) {
+		m.Produces = contentType
+	}
+}
@@ -1,9 +1,21 @@
 package boot
 
 import (
+    "context"
+    "errors"
+    "flag"
+    "log"
+    "os"
+    "os/signal"
+    "sync"
+    "syscall"
+
     "github.com/youbuwei/doeot-go/pkg/biz"
     "github.com/youbuwei/doeot-go/pkg/config"
+    "github.com/youbuwei/doeot-go/pkg/idempotency"
     "github.com/youbuwei/doeot-go/pkg/orm"
+    "github.com/youbuwei/doeot-go/pkg/ratelimit"
+    "github.com/youbuwei/doeot-go/pkg/validate"
     "gorm.io/gorm"
 )
 
@@ -13,17 +25,42 @@ type App struct {
     cfg     config.AppConfig
     db      *gorm.DB
     modules []biz.Module
+
+    authenticators authRegistry
+    middleware     middlewareRegistry
+    rateLimits     ratelimit.Store
+    idempotency    idempotency.Store
+    breakers       *breakerRegistry
+    configSources  []config.Provider
+
+    mu   sync.Mutex
+    stop context.CancelFunc
+    done chan struct{}
 }
 
-// New creates a new application for the given service name.
+// New creates a new application for the given service name. Configuration
+// is loaded with config.Load from the config files, environment and the
+// process flags; invalid configuration stops the process. The shared DB
+// also backs validation rules such as unique_db=users.phone.
 func New(serviceName string) *App {
-    cfg := config.Load(serviceName)
+    cfg, err := config.Load(serviceName, os.Args[1:]...)
+    if errors.Is(err, flag.ErrHelp) {
+        os.Exit(0)
+    }
+    if err != nil {
+        log.Fatalf("failed to load config: %v", err)
+    }
     db := orm.NewMySQL(cfg.MySQL)
+    validate.UseDB(db)
 
     return &App{
         name: serviceName,
         cfg:  cfg,
         db:   db,
+
+        rateLimits:  ratelimit.NewMemoryStore(),
+        idempotency: idempotency.NewMemoryStore(),
+        breakers:    newBreakerRegistry(),
     }
 }
 
@@ -37,17 +74,112 @@ func (a *App) RegisterModule(m biz.Module) {
     a.modules = append(a.modules, m)
 }
 
-// Run starts whichever transports are configured (HTTP or RPC).
+// WatchConfig makes Run keep the config.Value sections up to date with the
+// given providers, e.g. config.NewHTTPProvider for a config center. Later
+// providers override earlier ones.
+func (a *App) WatchConfig(providers ...config.Provider) {
+    a.configSources = append(a.configSources, providers...)
+}
+
+// Run starts every configured transport (HTTP and/or RPC) concurrently and
+// blocks until SIGINT/SIGTERM, Shutdown, or a transport failure.
+//
+// Lifecycle:
+//  1. the first fetch of the WatchConfig providers, which then keep
+//     reloading until Run returns
+//  2. OnStart of every biz.Starter module, in registration order
+//  3. serve; on stop, in-flight requests drain within Shutdown.Timeout
+//  4. OnStop of every biz.Stopper module, in reverse order
+//  5. the shared DB pool is closed last
+//
+// When one transport fails the others are stopped, and the first real error
+// is returned.
 func (a *App) Run() error {
-    switch {
-    case a.cfg.HTTP.Addr != "" && a.cfg.RPC.Addr == "":
-        return a.runHTTP()
-    case a.cfg.HTTP.Addr == "" && a.cfg.RPC.Addr != "":
-        return a.runRPC()
-    case a.cfg.HTTP.Addr != "" && a.cfg.RPC.Addr != "":
-        // For demo we just start HTTP; in real world you'd start both in goroutines.
-        return a.runHTTP()
-    default:
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    a.mu.Lock()
+    a.stop = stop
+    a.done = make(chan struct{})
+    a.mu.Unlock()
+    defer close(a.done)
+    defer a.closeDB()
+
+    if len(a.configSources) > 0 {
+        if err := config.Watch(ctx, a.configSources...); err != nil {
+            return err
+        }
+    }
+
+    started, err := a.startModules(ctx)
+    defer a.stopModules(started)
+    if err != nil {
+        return err
+    }
+
+    return a.serve(ctx)
+}
+
+// Shutdown asks a running App to stop gracefully and waits until Run has
+// returned or ctx is done. It is a no-op if Run has not been called.
+func (a *App) Shutdown(ctx context.Context) error {
+    a.mu.Lock()
+    stop, done := a.stop, a.done
+    a.mu.Unlock()
+    if stop == nil {
+        return nil
+    }
+
+    stop()
+    select {
+    case <-done:
         return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// serve runs all configured transports until ctx is cancelled or one fails.
+// Modules register their routes here, one transport after the other, so
+// registration never runs concurrently; only the listeners get their own
+// goroutines.
+func (a *App) serve(ctx context.Context) error {
+    var runners []func(context.Context) error
+    if a.cfg.HTTP.Addr != "" {
+        e, err := a.buildHTTP()
+        if err != nil {
+            return err
+        }
+        runners = append(runners, func(ctx context.Context) error { return a.runHTTP(ctx, e) })
+    }
+    if a.cfg.RPC.Addr != "" {
+        srv, err := a.buildRPC()
+        if err != nil {
+            return err
+        }
+        runners = append(runners, func(ctx context.Context) error { return a.runRPC(ctx, srv) })
+    }
+    if len(runners) == 0 {
+        return nil
+    }
+
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    errCh := make(chan error, len(runners))
+    for _, run := range runners {
+        go func(run func(context.Context) error) {
+            errCh <- run(ctx)
+        }(run)
+    }
+
+    var firstErr error
+    for range runners {
+        if err := <-errCh; err != nil && firstErr == nil {
+            firstErr = err
+        }
+        // Whichever transport exits first takes the others down with it.
+        cancel()
     }
+    return firstErr
 }
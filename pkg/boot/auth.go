@@ -0,0 +1,70 @@
+package boot
+
+import (
+	"errors"
+	"log"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+)
+
+// RegisterAuthenticator registers the Authenticator for an auth scheme used by
+// @Auth annotations, e.g. "login" or "admin". Routes whose scheme has no
+// authenticator reject every request.
+func (a *App) RegisterAuthenticator(scheme string, au biz.Authenticator) {
+	if a.authenticators == nil {
+		a.authenticators = make(authRegistry)
+	}
+	a.authenticators[scheme] = au
+}
+
+// authRegistry maps auth schemes to authenticators.
+type authRegistry map[string]biz.Authenticator
+
+// principalSetter is implemented by the transport contexts.
+type principalSetter interface {
+	biz.Context
+	setPrincipal(p *biz.Principal)
+}
+
+// check warns at registration time about schemes nobody can satisfy.
+func (r authRegistry) check(route string, meta *biz.RouteMeta) {
+	if isPublic(meta) {
+		return
+	}
+	if _, ok := r[meta.Auth]; !ok {
+		log.Printf("boot: %s requires auth scheme %q but no authenticator is registered; requests will be rejected", route, meta.Auth)
+	}
+}
+
+// authenticate runs the authenticator for meta.Auth and stores the resolved
+// principal on ctx. Public routes pass through untouched.
+func (r authRegistry) authenticate(ctx principalSetter, meta *biz.RouteMeta) error {
+	if isPublic(meta) {
+		return nil
+	}
+
+	au, ok := r[meta.Auth]
+	if !ok {
+		return errs.Unauthorized("unsupported auth scheme")
+	}
+
+	p, err := au.Authenticate(ctx, meta)
+	if err != nil {
+		var e *errs.Error
+		if errors.As(err, &e) {
+			return e
+		}
+		return errs.Unauthorized("unauthorized").WithCause(err)
+	}
+	if p == nil {
+		return errs.Unauthorized("unauthorized")
+	}
+
+	ctx.setPrincipal(p)
+	return nil
+}
+
+func isPublic(meta *biz.RouteMeta) bool {
+	return meta == nil || meta.Auth == "" || meta.Auth == biz.AuthNone
+}
@@ -0,0 +1,324 @@
+package boot
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+)
+
+// bindSources are the struct tags Bind reads, in order. Values found here
+// override what the JSON body decoded into the same field.
+var bindSources = []string{"path", "query", "header", "cookie", "form"}
+
+// Bind fills out (a pointer to struct) from the request:
+//   - JSON body, decoded with encoding/json
+//   - `path:"id"` URL parameters
+//   - `query:"q"` query parameters (repeated params fill slices)
+//   - `header:"X-Token"` request headers
+//   - `cookie:"sid"` cookies
+//   - `form:"name"` urlencoded / multipart form fields
+//   - `form:"avatar"` on biz.File fields: uploaded multipart parts
+//
+// Supported field types are strings, ints, uints, bools, floats,
+// time.Time (RFC 3339 or 2006-01-02), time.Duration, encoding.TextUnmarshaler,
+// slices of those and pointers for optional values. Malformed input is
+// reported as errs.BadRequest naming the offending field.
+func (ctx *echoContext) Bind(out any) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("Bind expects non-nil pointer")
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return errors.New("Bind expects pointer to struct")
+	}
+
+	req := ctx.c.Request()
+	ctype := mediaType(req)
+
+	if isJSON(ctype) || (ctype == "" && req.ContentLength > 0) {
+		if err := decodeJSON(req.Body, out); err != nil {
+			return err
+		}
+	}
+	if ctype == echo.MIMEApplicationForm || ctype == echo.MIMEMultipartForm {
+		if _, err := ctx.c.FormParams(); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return uploadTooLarge(tooLarge.Limit)
+			}
+			return errs.BadRequest("invalid form body").WithCause(err)
+		}
+	}
+
+	return ctx.bindFields(v)
+}
+
+// bindFields applies the tag sources to every exported field, descending
+// into embedded structs.
+func (ctx *echoContext) bindFields(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		if !fv.CanSet() {
+			continue
+		}
+		if field.Anonymous && fv.Kind() == reflect.Struct {
+			if err := ctx.bindFields(fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if isFileType(field.Type) {
+			if name := field.Tag.Get("form"); name != "" {
+				setFiles(fv, ctx.files(name))
+			}
+			continue
+		}
+
+		for _, src := range bindSources {
+			name := field.Tag.Get(src)
+			if name == "" {
+				continue
+			}
+			vals := ctx.lookup(src, name)
+			if len(vals) == 0 {
+				continue
+			}
+			if err := setField(fv, vals); err != nil {
+				msg := fmt.Sprintf("invalid %s parameter %q", src, name)
+				return errs.BadRequest(msg).
+					WithViolations(errs.FieldViolation{Field: name, Rule: "type", Message: msg}).
+					WithCause(err)
+			}
+		}
+	}
+	return nil
+}
+
+// lookup returns the raw values of name in the given source.
+func (ctx *echoContext) lookup(src, name string) []string {
+	req := ctx.c.Request()
+	switch src {
+	case "path":
+		if v := ctx.c.Param(name); v != "" {
+			return []string{v}
+		}
+	case "query":
+		return ctx.c.QueryParams()[name]
+	case "header":
+		return req.Header.Values(name)
+	case "cookie":
+		if c, err := req.Cookie(name); err == nil {
+			return []string{c.Value}
+		}
+	case "form":
+		// PostForm excludes query parameters; it is filled by FormParams in Bind.
+		return req.PostForm[name]
+	}
+	return nil
+}
+
+// files returns the uploaded parts of a multipart request named name.
+func (ctx *echoContext) files(name string) []*multipart.FileHeader {
+	form := ctx.c.Request().MultipartForm
+	if form == nil {
+		return nil
+	}
+	return form.File[name]
+}
+
+var fileType = reflect.TypeOf(biz.File{})
+
+// isFileType reports whether t is biz.File, *biz.File or a slice of either.
+func isFileType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == fileType
+}
+
+// setFiles stores the parts in a file field: all of them for slices, the
+// first one otherwise.
+func setFiles(fv reflect.Value, parts []*multipart.FileHeader) {
+	if len(parts) == 0 {
+		return
+	}
+	if fv.Kind() != reflect.Slice {
+		setFile(fv, parts[0])
+		return
+	}
+	s := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+	for i, p := range parts {
+		setFile(s.Index(i), p)
+	}
+	fv.Set(s)
+}
+
+func setFile(fv reflect.Value, fh *multipart.FileHeader) {
+	f := biz.NewFile(fh.Filename, fh.Size, fh.Header.Get(echo.HeaderContentType),
+		func() (io.ReadCloser, error) { return fh.Open() })
+	if fv.Kind() == reflect.Ptr {
+		fv.Set(reflect.ValueOf(f))
+		return
+	}
+	fv.Set(reflect.ValueOf(f).Elem())
+}
+
+// uploadTooLarge reports a request body above the route's @Upload maxSize.
+func uploadTooLarge(limit int64) *errs.Error {
+	return errs.PayloadTooLarge(fmt.Sprintf("upload exceeds the %d bytes limit", limit))
+}
+
+// decodeJSON decodes the body and turns decoder errors into BadRequest
+// messages that point at the bad field or position.
+func decodeJSON(body io.Reader, out any) error {
+	err := json.NewDecoder(body).Decode(out)
+	if err == nil || errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			return errs.BadRequest(fmt.Sprintf("invalid body: expected %s, got %s", typeErr.Type, typeErr.Value)).WithCause(err)
+		}
+		msg := fmt.Sprintf("invalid field %q: expected %s, got %s", field, typeErr.Type, typeErr.Value)
+		return errs.BadRequest(msg).
+			WithViolations(errs.FieldViolation{Field: field, Rule: "type", Message: msg}).
+			WithCause(err)
+	case errors.As(err, &syntaxErr):
+		return errs.BadRequest(fmt.Sprintf("malformed JSON body at offset %d", syntaxErr.Offset)).WithCause(err)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errs.BadRequest("malformed JSON body: unexpected end of input").WithCause(err)
+	}
+	return errs.BadRequest("invalid JSON body: " + err.Error()).WithCause(err)
+}
+
+func mediaType(req *http.Request) string {
+	ct := req.Header.Get(echo.HeaderContentType)
+	if ct == "" {
+		return ""
+	}
+	return baseMediaType(ct)
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == echo.MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	textType     = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setField assigns vals to fv: every value for slices, the first otherwise.
+// Pointers are allocated so an absent parameter stays nil.
+func setField(fv reflect.Value, vals []string) error {
+	switch {
+	case fv.Kind() == reflect.Ptr:
+		elem := reflect.New(fv.Type().Elem())
+		if err := setField(elem.Elem(), vals); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8:
+		s := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, v := range vals {
+			if err := setField(s.Index(i), []string{v}); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	}
+	return setValue(fv, vals[0])
+}
+
+func setValue(fv reflect.Value, s string) error {
+	switch fv.Type() {
+	case timeType:
+		t, err := parseTime(s)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	if fv.CanAddr() && fv.Addr().Type().Implements(textType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+		return nil
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+}
+
+// parseTime accepts RFC 3339 timestamps and plain dates.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
@@ -0,0 +1,236 @@
+package boot
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+	"github.com/youbuwei/doeot-go/pkg/idempotency"
+)
+
+func TestUploadTooLarge(t *testing.T) {
+	e, r := newTestRouter(&handlerChain{idempotency: idempotency.NewMemoryStore()})
+	r.POST("/avatar", func(ctx biz.Context) error {
+		var req struct {
+			File *biz.File `form:"file"`
+		}
+		if err := ctx.Bind(&req); err != nil {
+			return ctx.Result(nil, err)
+		}
+		return ctx.Result(req.File.Size, nil)
+	}, biz.WithUpload(64))
+	r.POST("/avatar/idempotent", func(ctx biz.Context) error {
+		return ctx.Result(nil, nil)
+	}, biz.WithUpload(64), biz.WithIdempotency(time.Hour))
+	if err := r.errs.err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path    string
+		chunked bool
+	}{
+		{"/avatar", false},
+		{"/avatar", true},
+		// Idempotency reads the body first to fingerprint it.
+		{"/avatar/idempotent", true},
+	} {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		part, _ := w.CreateFormFile("file", "a.png")
+		part.Write(bytes.Repeat([]byte("x"), 1024))
+		w.Close()
+
+		req := httptest.NewRequest(http.MethodPost, tc.path, &body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		req.Header.Set(biz.HeaderIdempotencyKey, "k1")
+		if tc.chunked {
+			// Without Content-Length the limit is hit while reading.
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusRequestEntityTooLarge || !bytes.Contains(rec.Body.Bytes(), []byte(errs.CodePayloadTooLarge)) {
+			t.Errorf("%s chunked=%v: status %d, body %s", tc.path, tc.chunked, rec.Code, rec.Body)
+		}
+	}
+}
+
+type bindReq struct {
+	ID      int64         `path:"id"`
+	Q       string        `query:"q"`
+	Tags    []string      `query:"tag"`
+	IDs     []int64       `query:"ids"`
+	Page    *int          `query:"page"`
+	Since   time.Time     `query:"since"`
+	Wait    time.Duration `query:"wait"`
+	Addr    netip.Addr    `query:"addr"`
+	Token   string        `header:"X-Token"`
+	Session string        `cookie:"sid"`
+	Name    string        `json:"name" form:"name"`
+	Amount  float64       `json:"amount"`
+	Agree   bool          `form:"agree"`
+}
+
+// bindRequest binds req on POST /orders/:id and returns the result.
+func bindRequest(req *http.Request) (bindReq, *httptest.ResponseRecorder) {
+	e, r := newTestRouter(&handlerChain{})
+	var got bindReq
+	r.POST("/orders/:id", func(ctx biz.Context) error {
+		return ctx.Result(nil, ctx.Bind(&got))
+	})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return got, rec
+}
+
+func TestBind(t *testing.T) {
+	two := 2
+	multipartBody := func() (string, string) {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		w.WriteField("name", "carol")
+		w.WriteField("agree", "1")
+		w.Close()
+		return body.String(), w.FormDataContentType()
+	}
+	mpBody, mpType := multipartBody()
+
+	cases := []struct {
+		name    string
+		target  string
+		ctype   string
+		body    string
+		headers map[string]string
+		cookies map[string]string
+		want    bindReq
+	}{
+		{
+			name:   "path and query",
+			target: "/orders/42?q=shoes&tag=a&tag=b&ids=7&ids=8&page=2&since=2026-01-02T03:04:05Z&wait=1500ms&addr=10.0.0.1",
+			want: bindReq{
+				ID: 42, Q: "shoes", Tags: []string{"a", "b"}, IDs: []int64{7, 8}, Page: &two,
+				Since: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				Wait:  1500 * time.Millisecond, Addr: netip.MustParseAddr("10.0.0.1"),
+			},
+		},
+		{
+			name:   "absent pointer and plain date",
+			target: "/orders/1?since=2026-01-02",
+			want:   bindReq{ID: 1, Since: time.Date(2026, 1, 2, 0, 0, 0, 0, time.Local)},
+		},
+		{
+			name:    "header and cookie",
+			target:  "/orders/1",
+			headers: map[string]string{"X-Token": "t0k"},
+			cookies: map[string]string{"sid": "s1"},
+			want:    bindReq{ID: 1, Token: "t0k", Session: "s1"},
+		},
+		{
+			name:   "JSON body",
+			target: "/orders/1?q=x",
+			ctype:  echo.MIMEApplicationJSON,
+			body:   `{"name":"bob","amount":9.5}`,
+			want:   bindReq{ID: 1, Q: "x", Name: "bob", Amount: 9.5},
+		},
+		{
+			name:   "urlencoded form",
+			target: "/orders/1?agree=false",
+			ctype:  echo.MIMEApplicationForm,
+			body:   "name=alice&agree=true",
+			want:   bindReq{ID: 1, Name: "alice", Agree: true},
+		},
+		{
+			name:   "multipart form",
+			target: "/orders/1",
+			ctype:  mpType,
+			body:   mpBody,
+			want:   bindReq{ID: 1, Name: "carol", Agree: true},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+			if tc.ctype != "" {
+				req.Header.Set(echo.HeaderContentType, tc.ctype)
+			}
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			for k, v := range tc.cookies {
+				req.AddCookie(&http.Cookie{Name: k, Value: v})
+			}
+			got, rec := bindRequest(req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d, body %s", rec.Code, rec.Body)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("bound %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBindBadRequest(t *testing.T) {
+	cases := []struct {
+		name   string
+		target string
+		ctype  string
+		body   string
+		msg    string // prefix of the message
+		field  string // expected violation; empty for none
+	}{
+		{"path", "/orders/x", "", "", `invalid path parameter "id"`, "id"},
+		{"int", "/orders/1?page=two", "", "", `invalid query parameter "page"`, "page"},
+		{"slice element", "/orders/1?ids=1&ids=x", "", "", `invalid query parameter "ids"`, "ids"},
+		{"time", "/orders/1?since=yesterday", "", "", `invalid query parameter "since"`, "since"},
+		{"duration", "/orders/1?wait=5", "", "", `invalid query parameter "wait"`, "wait"},
+		{"text unmarshaler", "/orders/1?addr=localhost", "", "", `invalid query parameter "addr"`, "addr"},
+		{"form", "/orders/1", echo.MIMEApplicationForm, "agree=maybe", `invalid form parameter "agree"`, "agree"},
+		{"JSON field type", "/orders/1", echo.MIMEApplicationJSON, `{"amount":"9.5"}`,
+			`invalid field "amount": expected float64, got string`, "amount"},
+		{"JSON body type", "/orders/1", echo.MIMEApplicationJSON, `[1]`, "invalid body: expected boot.bindReq, got array", ""},
+		{"JSON syntax", "/orders/1", echo.MIMEApplicationJSON, `{"name" "bob"}`, "malformed JSON body at offset 9", ""},
+		{"JSON truncated", "/orders/1", echo.MIMEApplicationJSON, `{"name":`, "malformed JSON body: unexpected end of input", ""},
+		{"JSON value", "/orders/1", echo.MIMEApplicationJSON, `{"since":"soon"}`, "invalid JSON body: ", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+			if tc.ctype != "" {
+				req.Header.Set(echo.HeaderContentType, tc.ctype)
+			}
+			_, rec := bindRequest(req)
+
+			var body struct {
+				Code    errs.Code     `json:"code"`
+				Msg     string        `json:"msg"`
+				Details *errs.Details `json:"details"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusBadRequest || body.Code != errs.CodeBadRequest {
+				t.Fatalf("status %d, body %s, want BAD_REQUEST", rec.Code, rec.Body)
+			}
+			if !strings.HasPrefix(body.Msg, tc.msg) {
+				t.Errorf("message %q, want %q", body.Msg, tc.msg)
+			}
+			switch {
+			case tc.field == "" && body.Details != nil:
+				t.Errorf("details %+v, want none", body.Details)
+			case tc.field != "" && (body.Details == nil || len(body.Details.Violations) != 1 ||
+				body.Details.Violations[0].Field != tc.field || body.Details.Violations[0].Rule != "type"):
+				t.Errorf("details %+v, want a type violation of %s", body.Details, tc.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package boot
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+)
+
+// incomingRequestID returns the caller's X-Request-ID, or a new ID when it
+// is missing or not a valid request ID (biz.ValidRequestID).
+func incomingRequestID(r *http.Request) string {
+	if id := r.Header.Get(biz.HeaderRequestID); biz.ValidRequestID(id) {
+		return id
+	}
+	return biz.NewRequestID()
+}
+
+// requestContext stores the request ID and the caller's language preference
+// (Accept-Language, also sent by jsonrpc clients) in the request context.
+func requestContext(r *http.Request, reqID string) context.Context {
+	ctx := biz.WithRequestID(r.Context(), reqID)
+	if l := r.Header.Get(biz.HeaderAcceptLanguage); l != "" {
+		ctx = biz.WithLocale(ctx, l)
+	}
+	return ctx
+}
+
+// reqInfo implements the transport-agnostic accessors of biz.Context.
+// echoContext and rpcContext both embed it, so endpoint code observes the
+// same behaviour over HTTP and JSON-RPC (which also travels over HTTP).
+type reqInfo struct {
+	req       *http.Request
+	meta      *biz.RouteMeta
+	clientIP  echo.IPExtractor
+	principal *biz.Principal
+	locals    map[string]any
+}
+
+func newReqInfo(req *http.Request, meta *biz.RouteMeta, clientIP echo.IPExtractor) reqInfo {
+	if meta == nil {
+		meta = &biz.RouteMeta{}
+	}
+	if clientIP == nil {
+		clientIP = echo.ExtractIPDirect()
+	}
+	return reqInfo{req: req, meta: meta, clientIP: clientIP}
+}
+
+// clientIPExtractor returns how a transport finds the client IP. Forwarding
+// headers are only believed on connections from trusted proxies (validated
+// IPs or CIDRs, see config.HTTPConfig), so clients cannot pick their own
+// address, e.g. to get a fresh @RateLimit bucket.
+func clientIPExtractor(trusted []string) echo.IPExtractor {
+	if len(trusted) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, p := range trusted {
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			opts = append(opts, echo.TrustIPRange(n))
+		} else if ip := net.ParseIP(p); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			opts = append(opts, echo.TrustIPRange(&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}))
+		}
+	}
+	xff := echo.ExtractIPFromXFFHeader(opts...)
+	realIP := echo.ExtractIPFromRealIPHeader(opts...)
+	return func(r *http.Request) string {
+		if r.Header.Get(echo.HeaderXForwardedFor) != "" {
+			return xff(r)
+		}
+		return realIP(r)
+	}
+}
+
+func (i *reqInfo) Header(key string) string {
+	return i.req.Header.Get(key)
+}
+
+func (i *reqInfo) Headers() http.Header {
+	return i.req.Header.Clone()
+}
+
+func (i *reqInfo) Cookie(name string) (string, bool) {
+	c, err := i.req.Cookie(name)
+	if err != nil {
+		return "", false
+	}
+	return c.Value, true
+}
+
+// RemoteAddr returns the client IP, honouring X-Forwarded-For / X-Real-IP
+// only when set by a trusted proxy in front of the service.
+func (i *reqInfo) RemoteAddr() string {
+	return i.clientIP(i.req)
+}
+
+func (i *reqInfo) Route() *biz.RouteMeta {
+	return i.meta
+}
+
+func (i *reqInfo) Get(key string) any {
+	return i.locals[key]
+}
+
+func (i *reqInfo) Set(key string, val any) {
+	if i.locals == nil {
+		i.locals = make(map[string]any)
+	}
+	i.locals[key] = val
+}
+
+func (i *reqInfo) Principal() *biz.Principal {
+	return i.principal
+}
+
+func (i *reqInfo) setPrincipal(p *biz.Principal) {
+	i.principal = p
+}
@@ -0,0 +1,110 @@
+package boot
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/config"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+)
+
+// jsonEqual reports whether got holds the same JSON value as want.
+func jsonEqual(t *testing.T, got []byte, want string) bool {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("response %q: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(g, w)
+}
+
+func TestErrorRendering(t *testing.T) {
+	fail := func() error {
+		return errs.Conflict("insufficient balance").
+			WithViolations(errs.FieldViolation{Field: "amount", Rule: "lte", Message: "amount exceeds the balance"}).
+			WithRetry(1500*time.Millisecond).
+			WithMetadata("balance", 10).
+			WithCause(errors.New("select balance: secret dsn"))
+	}
+	details := `{
+		"violations": [{"field": "amount", "rule": "lte", "message": "amount exceeds the balance"}],
+		"retry": {"after_seconds": 2},
+		"metadata": {"balance": 10}
+	}`
+
+	chain := &handlerChain{}
+	e, r := newTestRouter(chain)
+	e.Use(requestIDMiddleware)
+	r.POST("/pays", func(ctx biz.Context) error {
+		return ctx.Result(nil, fail())
+	})
+	s := newRPCServer("test", config.RPCConfig{}, authRegistry{}, chain)
+	(&rpcRouter{srv: s}).Handle("pay.create", func(biz.Context, json.RawMessage) (any, error) {
+		return nil, fail()
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/pays", nil)
+	req.Header.Set(biz.HeaderRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("HTTP: status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	want := `{"code": "CONFLICT", "msg": "insufficient balance", "request_id": "req-1", "details": ` + details + `}`
+	if !jsonEqual(t, rec.Body.Bytes(), want) {
+		t.Errorf("HTTP body %s, want %s", rec.Body, want)
+	}
+
+	body := `{"jsonrpc":"2.0","method":"pay.create","id":7}`
+	rpcReq := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rpcReq.Header.Set(biz.HeaderRequestID, "req-2")
+	rpcRec := httptest.NewRecorder()
+	s.handle(rpcRec, rpcReq)
+	want = `{"jsonrpc": "2.0", "id": 7, "error": {
+		"code": -32009,
+		"message": "insufficient balance",
+		"data": {"code": "CONFLICT", "request_id": "req-2", "details": ` + details + `}
+	}}`
+	if !jsonEqual(t, rpcRec.Body.Bytes(), want) {
+		t.Errorf("RPC body %s, want %s", rpcRec.Body, want)
+	}
+
+	for _, b := range []string{rec.Body.String(), rpcRec.Body.String()} {
+		if strings.Contains(b, "secret") {
+			t.Errorf("cause leaked to the client: %s", b)
+		}
+	}
+}
+
+func TestUnknownErrorRendering(t *testing.T) {
+	chain := &handlerChain{}
+	e, r := newTestRouter(chain)
+	r.GET("/boom", func(ctx biz.Context) error {
+		return ctx.Result(nil, errors.New("secret dsn"))
+	})
+	s := newRPCServer("test", config.RPCConfig{}, authRegistry{}, chain)
+	(&rpcRouter{srv: s}).Handle("boom", func(biz.Context, json.RawMessage) (any, error) {
+		return nil, errors.New("secret dsn")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+	if rec.Code != http.StatusInternalServerError || !jsonEqual(t, rec.Body.Bytes(), `{"code": "INTERNAL", "msg": "internal error", "request_id": ""}`) {
+		t.Errorf("HTTP: status %d, body %s", rec.Code, rec.Body)
+	}
+	reply := callRPC(t, s, "boom", "")
+	if reply.Error == nil || reply.Error.Code != -32000 || reply.Error.Message != "internal error" ||
+		reply.Error.Data.Code != errs.CodeInternal || reply.Error.Data.Details != nil {
+		t.Errorf("RPC: %+v", reply.Error)
+	}
+}
@@ -0,0 +1,110 @@
+package boot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+)
+
+func TestJoinPath(t *testing.T) {
+	cases := []struct{ prefix, path, want string }{
+		{"", "", "/"},
+		{"", "/", "/"},
+		{"", "users", "/users"},
+		{"", "/users/", "/users/"},
+		{"/", "", "/"},
+		{"/", "/users", "/users"},
+		{"/api", "", "/api"},
+		{"/api/", "/", "/api"},
+		{"/api", "users", "/api/users"},
+		{"api", "/users", "/api/users"},
+		{"/api/", "/users/:id", "/api/users/:id"},
+	}
+	for _, tc := range cases {
+		if got := joinPath(tc.prefix, tc.path); got != tc.want {
+			t.Errorf("joinPath(%q, %q) = %q, want %q", tc.prefix, tc.path, got, tc.want)
+		}
+	}
+}
+
+// routeInfo is what the group test handlers report about their route.
+type routeInfo struct {
+	Auth      string   `json:"auth"`
+	Tags      []string `json:"tags"`
+	BizTag    string   `json:"biz_tag"`
+	Principal string   `json:"principal"`
+}
+
+func TestGroupRoutes(t *testing.T) {
+	e, r := newTestRouter(&handlerChain{})
+	r.auth = authRegistry{"user": biz.AuthenticatorFunc(func(ctx biz.Context, _ *biz.RouteMeta) (*biz.Principal, error) {
+		if id := ctx.Header("X-User"); id != "" {
+			return &biz.Principal{ID: id}, nil
+		}
+		return nil, errs.Unauthorized("login required")
+	})}
+	info := func(ctx biz.Context) error {
+		meta := ctx.Route()
+		ri := routeInfo{Auth: meta.Auth, Tags: meta.Tags, BizTag: meta.BizTag}
+		if p := ctx.Principal(); p != nil {
+			ri.Principal = p.ID
+		}
+		return ctx.Result(ri, nil)
+	}
+
+	// Groups add their tags and set auth for their routes, which can
+	// override it; paths may omit the leading slash.
+	api := r.Group("/api/", biz.WithAuth("user"), biz.WithTags("api"))
+	v1 := api.Group("v1", biz.WithTags("v1"))
+	v1.GET("", info, biz.WithBizTag("v1.index"))
+	v1.GET("orders/:id", info, biz.WithBizTag("order.get"))
+	v1.POST("/orders", info, biz.WithTags("orders"), biz.WithBizTag("order.create"))
+	v1.GET("/health", info, biz.WithAuth(biz.AuthNone))
+	public := api.Group("/public", biz.WithAuth(biz.AuthNone))
+	public.ANY("ping", info)
+	r.Group("/").GET("status", info)
+
+	cases := []struct {
+		method, path, user string
+		wantCode           int
+		want               routeInfo
+	}{
+		{http.MethodGet, "/api/v1", "u1", http.StatusOK, routeInfo{Auth: "user", Tags: []string{"api", "v1"}, BizTag: "v1.index", Principal: "u1"}},
+		{http.MethodGet, "/api/v1/orders/7", "u1", http.StatusOK, routeInfo{Auth: "user", Tags: []string{"api", "v1"}, BizTag: "order.get", Principal: "u1"}},
+		{http.MethodGet, "/api/v1/orders/7", "", http.StatusUnauthorized, routeInfo{}},
+		{http.MethodPost, "/api/v1/orders", "u2", http.StatusOK, routeInfo{Auth: "user", Tags: []string{"api", "v1", "orders"}, BizTag: "order.create", Principal: "u2"}},
+		{http.MethodGet, "/api/v1/health", "", http.StatusOK, routeInfo{Auth: biz.AuthNone, Tags: []string{"api", "v1"}}},
+		{http.MethodDelete, "/api/public/ping", "", http.StatusOK, routeInfo{Auth: biz.AuthNone, Tags: []string{"api"}}},
+		{http.MethodGet, "/status", "", http.StatusOK, routeInfo{}},
+		{http.MethodGet, "/api/orders/7", "u1", http.StatusNotFound, routeInfo{}},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.user != "" {
+			req.Header.Set("X-User", tc.user)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != tc.wantCode {
+			t.Errorf("%s %s: status = %d, want %d: %s", tc.method, tc.path, rec.Code, tc.wantCode, rec.Body)
+			continue
+		}
+		if tc.wantCode != http.StatusOK {
+			continue
+		}
+		var body struct {
+			Data routeInfo `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: %v", tc.method, tc.path, err)
+		}
+		if !reflect.DeepEqual(body.Data, tc.want) {
+			t.Errorf("%s %s: route %+v, want %+v", tc.method, tc.path, body.Data, tc.want)
+		}
+	}
+}
@@ -3,43 +3,107 @@ package boot
 import (
 	"context"
 	"errors"
+	"log"
 	"net/http"
-	"reflect"
-	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
+	"github.com/youbuwei/doeot-go/pkg/apidoc"
 	"github.com/youbuwei/doeot-go/pkg/biz"
 	"github.com/youbuwei/doeot-go/pkg/errs"
 )
 
-func (a *App) runHTTP() error {
+// buildHTTP creates the HTTP server and registers every module's routes.
+func (a *App) buildHTTP() (*echo.Echo, error) {
 	e := echo.New()
 	e.HideBanner = true
+	e.IPExtractor = clientIPExtractor(a.cfg.HTTP.TrustedProxies)
 	e.Use(middleware.Recover())
+	e.Use(requestIDMiddleware)
 	e.Use(middleware.Logger())
 
-	router := &echoRouter{e: e}
+	router := &echoRouter{e: e, auth: a.authenticators, chain: a.handlerChain(), errs: &routeErrors{}}
 
 	for _, m := range a.modules {
 		m.RegisterHTTP(router)
 	}
+	if err := router.errs.err(); err != nil {
+		return nil, err
+	}
+
+	if a.cfg.Docs.Enabled {
+		mountDocs(e)
+	}
+	if a.cfg.Debug.Enabled {
+		mountBreakers(e, a.breakers)
+	}
+	return e, nil
+}
+
+// runHTTP serves e until ctx is cancelled.
+func (a *App) runHTTP(ctx context.Context, e *echo.Echo) error {
+	return a.serveUntil(ctx,
+		func() error { return e.Start(a.cfg.HTTP.Addr) },
+		e.Shutdown,
+	)
+}
 
-	return e.Start(a.cfg.HTTP.Addr)
+// mountDocs serves the OpenAPI document embedded at build time and the
+// docs page that renders it. Nothing is mounted if no spec was generated.
+func mountDocs(e *echo.Echo) {
+	spec := apidoc.Spec()
+	if spec == nil {
+		log.Printf("docs: enabled but no OpenAPI spec embedded, run go generate ./internal/modules")
+		return
+	}
+	e.GET("/openapi.json", func(c echo.Context) error {
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, spec)
+	})
+	e.GET("/docs", func(c echo.Context) error {
+		return c.HTMLBlob(http.StatusOK, apidoc.DocsHTML())
+	})
+}
+
+// requestIDMiddleware accepts a valid X-Request-ID from the caller or
+// generates one, echoes it on the response (picked up by the access log as
+// ${id}) and stores it in the request context for handlers, RPC clients and
+// GORM, together with the caller's Accept-Language.
+func requestIDMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		id := incomingRequestID(req)
+		c.Response().Header().Set(biz.HeaderRequestID, id)
+		c.SetRequest(req.WithContext(requestContext(req, id)))
+		return next(c)
+	}
 }
 
-// echoRouter adapts echo.Echo to biz.Router.
+// echoRouter adapts echo.Echo to biz.Router. Groups share the echo
+// instance and carry their path prefix and inherited options.
 type echoRouter struct {
-	e *echo.Echo
+	e      *echo.Echo
+	auth   authRegistry
+	chain  *handlerChain
+	errs   *routeErrors
+	prefix string
+	opts   []biz.RouteOption
 }
 
 func (r *echoRouter) wrap(h biz.HandlerFunc, meta *biz.RouteMeta) echo.HandlerFunc {
-	// meta can be used to attach middleware like auth/metrics; omitted in demo.
-	_ = meta
-
 	return func(c echo.Context) error {
-		ctx := newEchoContext(c)
+		ctx := newEchoContext(c, meta)
+		if err := r.auth.authenticate(ctx, meta); err != nil {
+			return ctx.Result(nil, err)
+		}
+		if meta.MaxUploadSize > 0 {
+			req := c.Request()
+			if req.ContentLength > meta.MaxUploadSize {
+				return ctx.Result(nil, uploadTooLarge(meta.MaxUploadSize))
+			}
+			req.Body = http.MaxBytesReader(c.Response(), req.Body, meta.MaxUploadSize)
+		}
 		return h(ctx)
 	}
 }
@@ -53,142 +117,158 @@ func buildRouteMeta(opts []biz.RouteOption) *biz.RouteMeta {
 }
 
 func (r *echoRouter) GET(path string, h biz.HandlerFunc, opts ...biz.RouteOption) {
-	meta := buildRouteMeta(opts)
-	r.e.GET(path, r.wrap(h, meta))
+	r.add(http.MethodGet, path, h, opts)
 }
 
 func (r *echoRouter) POST(path string, h biz.HandlerFunc, opts ...biz.RouteOption) {
-	meta := buildRouteMeta(opts)
-	r.e.POST(path, r.wrap(h, meta))
+	r.add(http.MethodPost, path, h, opts)
 }
 
 func (r *echoRouter) PUT(path string, h biz.HandlerFunc, opts ...biz.RouteOption) {
-	meta := buildRouteMeta(opts)
-	r.e.PUT(path, r.wrap(h, meta))
+	r.add(http.MethodPut, path, h, opts)
 }
 
 func (r *echoRouter) DELETE(path string, h biz.HandlerFunc, opts ...biz.RouteOption) {
-	meta := buildRouteMeta(opts)
-	r.e.DELETE(path, r.wrap(h, meta))
+	r.add(http.MethodDelete, path, h, opts)
 }
 
-// echoContext implements biz.Context on top of echo.Context.
-type echoContext struct {
-	c echo.Context
+func (r *echoRouter) PATCH(path string, h biz.HandlerFunc, opts ...biz.RouteOption) {
+	r.add(http.MethodPatch, path, h, opts)
 }
 
-func newEchoContext(c echo.Context) *echoContext {
-	return &echoContext{c: c}
+func (r *echoRouter) HEAD(path string, h biz.HandlerFunc, opts ...biz.RouteOption) {
+	r.add(http.MethodHead, path, h, opts)
 }
 
-func (ctx *echoContext) RequestContext() context.Context {
-	return ctx.c.Request().Context()
+func (r *echoRouter) OPTIONS(path string, h biz.HandlerFunc, opts ...biz.RouteOption) {
+	r.add(http.MethodOptions, path, h, opts)
 }
 
-func (ctx *echoContext) RequestID() string {
-	id := ctx.c.Request().Header.Get("X-Request-ID")
-	if id == "" {
-		id = ctx.c.Response().Header().Get("X-Request-ID")
-	}
-	return id
+// ANY registers the handler for every HTTP method.
+func (r *echoRouter) ANY(path string, h biz.HandlerFunc, opts ...biz.RouteOption) {
+	r.add("ANY", path, h, opts)
 }
 
-// Bind supports a tiny subset of binding rules:
-//   - JSON body (via echo.Bind)
-//   - `path:"name"` tags from URL parameters
-func (ctx *echoContext) Bind(out any) error {
-	// First let echo try JSON/query/form binding.
-	if err := ctx.c.Bind(out); err != nil {
-		// ignore here; we still try manual binding below
-		_ = err
+// Group returns a router that prefixes paths with prefix and applies opts
+// before each route's own options, so routes inherit auth, tags, etc.
+func (r *echoRouter) Group(prefix string, opts ...biz.RouteOption) biz.Router {
+	return &echoRouter{
+		e:      r.e,
+		auth:   r.auth,
+		chain:  r.chain,
+		errs:   r.errs,
+		prefix: joinPath(r.prefix, prefix),
+		opts:   append(append([]biz.RouteOption{}, r.opts...), opts...),
 	}
+}
+
+func (r *echoRouter) add(method, path string, h biz.HandlerFunc, opts []biz.RouteOption) {
+	path, meta := r.route(path, opts)
+	route := method + " " + path
+	r.auth.check(route, meta)
 
-	v := reflect.ValueOf(out)
-	if v.Kind() != reflect.Ptr || v.IsNil() {
-		return errors.New("Bind expects non-nil pointer")
+	h, err := r.chain.build(route, h, meta)
+	if err != nil {
+		r.errs.add(route, err)
+		return
 	}
-	v = v.Elem()
-	if v.Kind() != reflect.Struct {
-		return errors.New("Bind expects pointer to struct")
+	if method == "ANY" {
+		r.e.Any(path, r.wrap(h, meta))
+		return
 	}
+	r.e.Add(method, path, r.wrap(h, meta))
+}
 
-	t := v.Type()
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		fv := v.Field(i)
-		if !fv.CanSet() {
-			continue
-		}
+// route resolves the full path and metadata of a route in this group.
+func (r *echoRouter) route(path string, opts []biz.RouteOption) (string, *biz.RouteMeta) {
+	all := append(append([]biz.RouteOption{}, r.opts...), opts...)
+	return joinPath(r.prefix, path), buildRouteMeta(all)
+}
 
-		if pathName := field.Tag.Get("path"); pathName != "" {
-			val := ctx.c.Param(pathName)
-			if val == "" {
-				continue
-			}
-			if err := setValue(fv, val); err != nil {
-				return err
-			}
+// joinPath mounts path under prefix. The result always starts with "/",
+// whichever of the two lacks it or is empty.
+func joinPath(prefix, path string) string {
+	full := strings.TrimSuffix(prefix, "/")
+	if path != "" && path != "/" {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
 		}
+		full += path
 	}
+	if !strings.HasPrefix(full, "/") {
+		full = "/" + full
+	}
+	return full
+}
 
-	return nil
+// echoContext implements biz.Context on top of echo.Context.
+type echoContext struct {
+	reqInfo
+	c echo.Context
 }
 
-func setValue(fv reflect.Value, s string) error {
-	switch fv.Kind() {
-	case reflect.String:
-		fv.SetString(s)
-		return nil
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		n, err := strconv.ParseInt(s, 10, 64)
-		if err != nil {
-			return err
-		}
-		fv.SetInt(n)
-		return nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		n, err := strconv.ParseUint(s, 10, 64)
-		if err != nil {
-			return err
-		}
-		fv.SetUint(n)
-		return nil
-	default:
-		return errors.New("unsupported kind in setValue")
-	}
+func newEchoContext(c echo.Context, meta *biz.RouteMeta) *echoContext {
+	return &echoContext{reqInfo: newReqInfo(c.Request(), meta, c.Echo().IPExtractor), c: c}
+}
+
+func (ctx *echoContext) RequestContext() context.Context {
+	return ctx.c.Request().Context()
+}
+
+func (ctx *echoContext) RequestID() string {
+	return biz.RequestIDFrom(ctx.RequestContext())
 }
 
 func (ctx *echoContext) JSON(status int, body any) error {
 	return ctx.c.JSON(status, body)
 }
 
+// logServerError logs errors answered with a 5xx status, including the cause
+// hidden from the client, so they can be found by request ID.
+func (ctx *echoContext) logServerError(err error) {
+	var e *errs.Error
+	if errors.As(err, &e) && errs.HTTPStatus(e.Code) < http.StatusInternalServerError {
+		return
+	}
+	req := ctx.c.Request()
+	log.Printf("http: %s %s request_id=%s err=%v", req.Method, req.URL.Path, ctx.RequestID(), err)
+}
+
 // Result turns (data, err) into a standardized HTTP response shape.
+// Raw response types (biz.FileResponse, biz.Redirect, biz.Stream) and data
+// of routes with a non-JSON @Produces are written without the envelope.
 func (ctx *echoContext) Result(data any, err error) error {
 	if err == nil {
+		if ok, werr := ctx.writeRaw(data); ok {
+			return werr
+		}
 		return ctx.c.JSON(http.StatusOK, map[string]any{
 			"code": errs.CodeOK,
 			"data": data,
 		})
 	}
 
+	ctx.logServerError(err)
 	var e *errs.Error
 	if errors.As(err, &e) {
-		status := http.StatusInternalServerError
-		switch e.Code {
-		case errs.CodeBadRequest:
-			status = http.StatusBadRequest
-		case errs.CodeNotFound:
-			status = http.StatusNotFound
+		if e.Details != nil && e.Details.Retry != nil {
+			ctx.c.Response().Header().Set("Retry-After", retryAfter(e.Details.Retry.After))
 		}
-		return ctx.c.JSON(status, map[string]any{
-			"code": e.Code,
-			"msg":  e.Msg,
-		})
+		body := map[string]any{
+			"code":       e.Code,
+			"msg":        e.Msg,
+			"request_id": ctx.RequestID(),
+		}
+		if e.Details != nil {
+			body["details"] = e.Details
+		}
+		return ctx.c.JSON(errs.HTTPStatus(e.Code), body)
 	}
 
 	// Fallback for unknown errors.
 	return ctx.c.JSON(http.StatusInternalServerError, map[string]any{
-		"code": errs.CodeInternal,
-		"msg":  "internal error",
+		"code":       errs.CodeInternal,
+		"msg":        "internal error",
+		"request_id": ctx.RequestID(),
 	})
 }
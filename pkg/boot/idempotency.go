@@ -0,0 +1,255 @@
+package boot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+	"github.com/youbuwei/doeot-go/pkg/idempotency"
+)
+
+// maxIdempotencyKeyLen bounds the Idempotency-Key accepted from clients.
+const maxIdempotencyKeyLen = 255
+
+// idempotencyStoreTimeout bounds each store call. The calls do not use the
+// request's deadline: a key left "in progress" because the client went
+// away or the route timed out would reject every retry until it expires.
+const idempotencyStoreTimeout = 5 * time.Second
+
+// SetIdempotencyStore replaces the in-process store behind @Idempotent,
+// e.g. with idempotency.NewGormStore(app.DB()) so every replica sees the
+// same keys. It must be called before Run.
+func (a *App) SetIdempotencyStore(s idempotency.Store) {
+	a.idempotency = s
+}
+
+// idempotent wraps h with the route's @Idempotent. Calls without an
+// Idempotency-Key run normally. The first call with a key runs h and its
+// outcome is stored; later calls with the same key and request replay it
+// without running h (or the named middleware). Keys are scoped to the route
+// and the caller.
+//
+// Unlike rate limiting this fails closed: running a payment twice because
+// the store is down is worse than rejecting the call.
+func (c *handlerChain) idempotent(route string, h biz.HandlerFunc, meta *biz.RouteMeta) (biz.HandlerFunc, error) {
+	ttl := meta.IdempotencyTTL
+	if ttl <= 0 {
+		return h, nil
+	}
+	if c.idempotency == nil {
+		return nil, errors.New("idempotency store not configured")
+	}
+
+	return func(ctx biz.Context) error {
+		key := ctx.Header(biz.HeaderIdempotencyKey)
+		if key == "" {
+			return h(ctx)
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			return ctx.Result(nil, errs.BadRequest("Idempotency-Key is too long"))
+		}
+		fp, err := requestFingerprint(ctx)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return ctx.Result(nil, uploadTooLarge(tooLarge.Limit))
+			}
+			return ctx.Result(nil, errs.BadRequest("failed to read request").WithCause(err))
+		}
+
+		store, rc := c.idempotency, ctx.RequestContext()
+		rawType := rawContentType(ctx)
+		key = idempotencyStoreKey(route, ctx.Principal(), batchEntryKey(ctx, key))
+		sc, cancel := storeContext(rc)
+		rec, err := store.Begin(sc, key, fp, ttl)
+		cancel()
+		if err != nil {
+			return ctx.Result(nil, errs.Internal("idempotency store unavailable").WithCause(err))
+		}
+		if rec != nil {
+			return replay(ctx, rec, fp)
+		}
+
+		completed := false
+		defer func() {
+			// Panics and failures worth retrying leave the key free.
+			if !completed {
+				sc, cancel := storeContext(rc)
+				defer cancel()
+				if err := store.Release(sc, key); err != nil {
+					log.Printf("boot: idempotency %s: release: %v", route, err)
+				}
+			}
+		}()
+
+		// The outcome is stored before it is written, so duplicates arriving
+		// right after the response see a completed key.
+		return h(observeResult(ctx, func(data any, err error) {
+			rec, ok := idempotentRecord(data, err, rawType)
+			if !ok {
+				return
+			}
+			sc, cancel := storeContext(rc)
+			defer cancel()
+			if err := store.Complete(sc, key, rec); err != nil {
+				log.Printf("boot: idempotency %s: complete: %v", route, err)
+				return
+			}
+			completed = true
+		}))
+	}, nil
+}
+
+// batchEntryKey scopes key to the entry of a JSON-RPC batch: every entry
+// carries the batch's Idempotency-Key header, so two different calls in one
+// batch must not share it.
+func batchEntryKey(ctx biz.Context, key string) string {
+	if rc, ok := rpcContextOf(ctx); ok && rc.batchEntry != "" {
+		return key + "\x00" + rc.batchEntry
+	}
+	return key
+}
+
+// storeContext keeps the values of the request context (request ID, ...)
+// but not its cancellation or deadline.
+func storeContext(rc context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(rc), idempotencyStoreTimeout)
+}
+
+// replay answers a call whose key is already known.
+func replay(ctx biz.Context, rec *idempotency.Record, fp string) error {
+	switch {
+	case rec.Fingerprint != fp:
+		return ctx.Result(nil, errs.BadRequest("Idempotency-Key was already used with a different request"))
+	case !rec.Done:
+		return ctx.Result(nil, errs.Conflict("a request with this Idempotency-Key is still in progress"))
+	case rec.ErrCode != "":
+		e := &errs.Error{Code: errs.Code(rec.ErrCode), Msg: rec.ErrMsg}
+		if len(rec.ErrDetails) > 0 {
+			e.Details = new(errs.Details)
+			if err := json.Unmarshal(rec.ErrDetails, e.Details); err != nil {
+				e.Details = nil
+			}
+		}
+		return ctx.Result(nil, e)
+	case rec.ContentType != "":
+		// Written as is by the route's non-JSON @Produces.
+		return ctx.Result([]byte(rec.Result), nil)
+	}
+	return ctx.Result(rec.Result, nil)
+}
+
+// rawContentType returns the @Produces type of an HTTP route writing []byte
+// and string data without the JSON envelope, "" otherwise. Such data is
+// stored as is, so a replay writes the same bytes.
+func rawContentType(ctx biz.Context) string {
+	if _, ok := contextAs[*echoContext](ctx); !ok {
+		return ""
+	}
+	produces := ctx.Route().Produces
+	if produces == "" || isJSON(baseMediaType(produces)) {
+		return ""
+	}
+	return produces
+}
+
+// idempotentRecord turns a call outcome into a record. Server failures
+// (internal errors, timeouts, ...) and responses that cannot be replayed
+// (files, redirects, streams) are not stored, so the key can be retried.
+// rawType is the rawContentType of the route.
+func idempotentRecord(data any, err error, rawType string) (idempotency.Record, bool) {
+	if err != nil {
+		var e *errs.Error
+		if isServerFailure(err) || !errors.As(err, &e) {
+			return idempotency.Record{}, false
+		}
+		rec := idempotency.Record{ErrCode: string(e.Code), ErrMsg: e.Msg}
+		if e.Details != nil {
+			raw, merr := json.Marshal(e.Details)
+			if merr != nil {
+				return idempotency.Record{}, false
+			}
+			rec.ErrDetails = raw
+		}
+		return rec, true
+	}
+
+	switch data.(type) {
+	case biz.FileResponse, *biz.FileResponse, biz.Redirect, *biz.Redirect,
+		biz.Stream, *biz.Stream, io.Reader:
+		return idempotency.Record{}, false
+	}
+	if rawType != "" {
+		switch v := data.(type) {
+		case []byte:
+			return idempotency.Record{Result: bytes.Clone(v), ContentType: rawType}, true
+		case string:
+			return idempotency.Record{Result: []byte(v), ContentType: rawType}, true
+		}
+	}
+	raw, merr := json.Marshal(data)
+	if merr != nil {
+		return idempotency.Record{}, false
+	}
+	return idempotency.Record{Result: raw}, true
+}
+
+// idempotencyStoreKey scopes a client key to the route and caller, hashed so
+// it fits any store.
+func idempotencyStoreKey(route string, p *biz.Principal, key string) string {
+	caller := ""
+	if p != nil {
+		caller = p.ID
+	}
+	sum := sha256.Sum256([]byte(route + "\x00" + caller + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprinter is implemented by the transport contexts.
+type fingerprinter interface {
+	fingerprint() ([]byte, error)
+}
+
+// requestFingerprint hashes what identifies a request on its transport.
+func requestFingerprint(ctx biz.Context) (string, error) {
+	f, ok := contextAs[fingerprinter](ctx)
+	if !ok {
+		return "", errors.New("request cannot be fingerprinted")
+	}
+	b, err := f.fingerprint()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// fingerprint covers the method, URI (path params and query) and body. The
+// body is buffered and restored for Bind.
+func (ctx *echoContext) fingerprint() ([]byte, error) {
+	req := ctx.c.Request()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var b bytes.Buffer
+	b.WriteString(req.Method + " " + req.URL.RequestURI() + "\n")
+	b.Write(body)
+	return b.Bytes(), nil
+}
+
+// fingerprint covers the params; the method is part of the key's scope.
+func (c *rpcContext) fingerprint() ([]byte, error) {
+	return c.params, nil
+}
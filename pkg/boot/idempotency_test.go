@@ -0,0 +1,197 @@
+package boot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/config"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+	"github.com/youbuwei/doeot-go/pkg/idempotency"
+)
+
+// ctxStore fails like a database store once ctx is done.
+type ctxStore struct {
+	idempotency.Store
+}
+
+func (s ctxStore) Begin(ctx context.Context, key, fp string, ttl time.Duration) (*idempotency.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Store.Begin(ctx, key, fp, ttl)
+}
+
+func (s ctxStore) Complete(ctx context.Context, key string, rec idempotency.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Store.Complete(ctx, key, rec)
+}
+
+func (s ctxStore) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Store.Release(ctx, key)
+}
+
+func newTestRouter(chain *handlerChain) (*echo.Echo, *echoRouter) {
+	e := echo.New()
+	return e, &echoRouter{e: e, auth: authRegistry{}, chain: chain, errs: &routeErrors{}}
+}
+
+func postWithKey(ctx context.Context, e *echo.Echo, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"amount":100}`)).WithContext(ctx)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(biz.HeaderIdempotencyKey, key)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotentReleasesKeyAfterClientDisconnect(t *testing.T) {
+	e, r := newTestRouter(&handlerChain{idempotency: ctxStore{idempotency.NewMemoryStore()}})
+
+	calls := 0
+	var disconnect context.CancelFunc
+	r.POST("/pays", func(ctx biz.Context) error {
+		calls++
+		if calls == 1 {
+			disconnect()
+			return ctx.Result(nil, errs.Internal("payment gateway unreachable"))
+		}
+		return ctx.Result("paid", nil)
+	}, biz.WithIdempotency(24*time.Hour))
+	if err := r.errs.err(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	disconnect = cancel
+	postWithKey(ctx, e, "/pays", "k1")
+
+	rec := postWithKey(context.Background(), e, "/pays", "k1")
+	if rec.Code != http.StatusOK || calls != 2 {
+		t.Fatalf("retry: status %d after %d calls, want 200 after 2; body %s", rec.Code, calls, rec.Body)
+	}
+}
+
+func TestIdempotentWithTimeout(t *testing.T) {
+	e, r := newTestRouter(&handlerChain{idempotency: ctxStore{idempotency.NewMemoryStore()}})
+
+	calls := 0
+	r.POST("/slow", func(ctx biz.Context) error {
+		calls++
+		<-ctx.RequestContext().Done()
+		if calls == 1 {
+			// Finished just past the deadline: the result is stored.
+			return ctx.Result("paid", nil)
+		}
+		return ctx.Result(nil, ctx.RequestContext().Err())
+	}, biz.WithTimeout(10*time.Millisecond), biz.WithIdempotency(24*time.Hour))
+	if err := r.errs.err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := postWithKey(context.Background(), e, "/slow", "k1"); rec.Code != http.StatusOK {
+		t.Fatalf("first call: status %d, body %s", rec.Code, rec.Body)
+	}
+	rec := postWithKey(context.Background(), e, "/slow", "k1")
+	if rec.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("replay: status %d after %d calls, want 200 after 1; body %s", rec.Code, calls, rec.Body)
+	}
+
+	// A call failing with TIMEOUT leaves its key free for a retry.
+	if rec := postWithKey(context.Background(), e, "/slow", "k2"); rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("timed out call: status %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := postWithKey(context.Background(), e, "/slow", "k2"); rec.Code != http.StatusGatewayTimeout || calls != 3 {
+		t.Fatalf("retry: status %d after %d calls, want 504 after 3; body %s", rec.Code, calls, rec.Body)
+	}
+}
+
+func TestIdempotentReplayIsByteIdentical(t *testing.T) {
+	e, r := newTestRouter(&handlerChain{idempotency: idempotency.NewMemoryStore()})
+
+	calls := 0
+	routes := []struct {
+		path     string
+		produces string
+		data     any
+	}{
+		{"/export.csv", "text/csv; charset=utf-8", "id,amount\n1,100\n"},
+		{"/receipt.pdf", "application/pdf", []byte("%PDF-1.7\x00\xff")},
+		{"/text", "text/plain", struct{ ID int }{7}}, // not raw: JSON envelope
+		{"/json", "", map[string]any{"id": 7}},
+	}
+	for _, rt := range routes {
+		data := rt.data
+		opts := []biz.RouteOption{biz.WithIdempotency(time.Hour)}
+		if rt.produces != "" {
+			opts = append(opts, biz.WithProduces(rt.produces))
+		}
+		r.POST(rt.path, func(ctx biz.Context) error {
+			calls++
+			return ctx.Result(data, nil)
+		}, opts...)
+	}
+	if err := r.errs.err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rt := range routes {
+		calls = 0
+		first := postWithKey(context.Background(), e, rt.path, "k1")
+		replayed := postWithKey(context.Background(), e, rt.path, "k1")
+		if calls != 1 {
+			t.Errorf("%s: handler ran %d times", rt.path, calls)
+		}
+		if first.Code != http.StatusOK || replayed.Code != first.Code ||
+			replayed.Header().Get(echo.HeaderContentType) != first.Header().Get(echo.HeaderContentType) ||
+			replayed.Body.String() != first.Body.String() {
+			t.Errorf("%s: replayed %d %q %q, first %d %q %q", rt.path,
+				replayed.Code, replayed.Header().Get(echo.HeaderContentType), replayed.Body,
+				first.Code, first.Header().Get(echo.HeaderContentType), first.Body)
+		}
+	}
+}
+
+func TestIdempotentBatchEntries(t *testing.T) {
+	s := newRPCServer("test", config.RPCConfig{BatchConcurrency: 1}, authRegistry{}, &handlerChain{idempotency: idempotency.NewMemoryStore()})
+	calls := 0
+	(&rpcRouter{srv: s}).Handle("Pay.Create", func(_ biz.Context, params json.RawMessage) (any, error) {
+		calls++
+		return "paid " + string(params), nil
+	}, biz.WithIdempotency(time.Hour))
+
+	send := func(body string) string {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(biz.HeaderIdempotencyKey, "k1")
+		rec := httptest.NewRecorder()
+		s.handle(rec, req)
+		return rec.Body.String()
+	}
+
+	batch := `[{"jsonrpc":"2.0","method":"Pay.Create","params":100,"id":1},` +
+		`{"jsonrpc":"2.0","method":"Pay.Create","params":200,"id":2}]`
+	first := send(batch)
+	if calls != 2 || strings.Contains(first, `"error"`) {
+		t.Fatalf("batch: %d calls, response %s", calls, first)
+	}
+	if again := send(batch); again != first || calls != 2 {
+		t.Fatalf("retried batch: %d calls, response %s, want %s", calls, again, first)
+	}
+	// The key of a single call is not the key of a batch entry.
+	single := send(`{"jsonrpc":"2.0","method":"Pay.Create","params":300,"id":1}`)
+	if calls != 3 || strings.Contains(single, `"error"`) {
+		t.Fatalf("single call: %d calls, response %s", calls, single)
+	}
+}
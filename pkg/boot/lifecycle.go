@@ -0,0 +1,90 @@
+package boot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+)
+
+// serveUntil runs serve until it fails or ctx is cancelled. On cancellation
+// it calls shutdown with the configured drain timeout and waits for it, so
+// in-flight requests finish before the caller moves on.
+func (a *App) serveUntil(ctx context.Context, serve func() error, shutdown func(context.Context) error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- serve()
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	drainCtx, cancel := a.drainContext()
+	defer cancel()
+	return shutdown(drainCtx)
+}
+
+// drainContext returns a fresh context bounded by the shutdown timeout.
+func (a *App) drainContext() (context.Context, context.CancelFunc) {
+	if a.cfg.Shutdown.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), a.cfg.Shutdown.Timeout)
+}
+
+// startModules calls OnStart on every module implementing biz.Starter, in
+// registration order. It returns the modules that started successfully so
+// they can be stopped even when a later one fails.
+func (a *App) startModules(ctx context.Context) ([]biz.Module, error) {
+	started := make([]biz.Module, 0, len(a.modules))
+	for _, m := range a.modules {
+		if s, ok := m.(biz.Starter); ok {
+			if err := s.OnStart(ctx); err != nil {
+				return started, fmt.Errorf("module %s: start: %w", m.Name(), err)
+			}
+		}
+		started = append(started, m)
+	}
+	return started, nil
+}
+
+// stopModules calls OnStop in reverse registration order. Errors are logged
+// so one failing module does not prevent the others from cleaning up.
+func (a *App) stopModules(mods []biz.Module) {
+	ctx, cancel := a.drainContext()
+	defer cancel()
+
+	for i := len(mods) - 1; i >= 0; i-- {
+		s, ok := mods[i].(biz.Stopper)
+		if !ok {
+			continue
+		}
+		if err := s.OnStop(ctx); err != nil {
+			log.Printf("boot: module %s: stop: %v", mods[i].Name(), err)
+		}
+	}
+}
+
+// closeDB releases the shared connection pool.
+func (a *App) closeDB() {
+	if a.db == nil {
+		return
+	}
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		log.Printf("boot: get sql.DB: %v", err)
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		log.Printf("boot: close db: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package boot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/youbuwei/doeot-go/pkg/config"
+)
+
+// callLog records lifecycle calls across modules.
+type callLog struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (l *callLog) add(call string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, call)
+}
+
+// lifecycleModule is a module with OnStart and OnStop hooks.
+type lifecycleModule struct {
+	routesModule
+	name     string
+	log      *callLog
+	startErr error
+}
+
+func (m *lifecycleModule) Name() string {
+	return m.name
+}
+
+func (m *lifecycleModule) OnStart(ctx context.Context) error {
+	m.log.add("start " + m.name)
+	return m.startErr
+}
+
+func (m *lifecycleModule) OnStop(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		m.log.add("stop " + m.name + " without deadline")
+		return nil
+	}
+	m.log.add("stop " + m.name)
+	return nil
+}
+
+func TestModulesStopInReverseOrder(t *testing.T) {
+	log := &callLog{}
+	a := &App{
+		name:     "test",
+		cfg:      config.AppConfig{Shutdown: config.ShutdownConfig{Timeout: time.Second}},
+		breakers: newBreakerRegistry(),
+	}
+	a.RegisterModule(&lifecycleModule{name: "a", log: log})
+	// A module without hooks is skipped on both ends.
+	a.RegisterModule(routesModule{})
+	a.RegisterModule(&lifecycleModule{name: "b", log: log})
+
+	if err := a.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"start a", "start b", "stop b", "stop a"}
+	if !reflect.DeepEqual(log.calls, want) {
+		t.Errorf("calls = %q, want %q", log.calls, want)
+	}
+}
+
+func TestModuleStartFailureStopsStartedOnes(t *testing.T) {
+	log := &callLog{}
+	a := &App{
+		name:     "test",
+		cfg:      config.AppConfig{Shutdown: config.ShutdownConfig{Timeout: time.Second}},
+		breakers: newBreakerRegistry(),
+	}
+	a.RegisterModule(&lifecycleModule{name: "a", log: log})
+	a.RegisterModule(&lifecycleModule{name: "b", log: log})
+	a.RegisterModule(&lifecycleModule{name: "c", log: log, startErr: errors.New("boom")})
+	a.RegisterModule(&lifecycleModule{name: "d", log: log})
+
+	err := a.Run()
+	if err == nil || err.Error() != "module c: start: boom" {
+		t.Fatalf("Run = %v", err)
+	}
+	want := []string{"start a", "start b", "start c", "stop b", "stop a"}
+	if !reflect.DeepEqual(log.calls, want) {
+		t.Errorf("calls = %q, want %q", log.calls, want)
+	}
+}
+
+func TestServeUntilDrain(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	a := &App{cfg: config.AppConfig{Shutdown: config.ShutdownConfig{Timeout: timeout}}}
+
+	cases := []struct {
+		name string
+		// drain is how long in-flight requests take after shutdown starts.
+		drain   time.Duration
+		wantErr error
+	}{
+		{"drained", 5 * time.Millisecond, nil},
+		{"drain timeout", time.Hour, context.DeadlineExceeded},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			closed := make(chan struct{})
+			serve := func() error {
+				<-closed
+				return http.ErrServerClosed
+			}
+			shutdown := func(ctx context.Context) error {
+				close(closed)
+				select {
+				case <-time.After(tc.drain):
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			start := time.Now()
+			err := a.serveUntil(ctx, serve, shutdown)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("serveUntil = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if d := time.Since(start); d < timeout || d > 10*timeout {
+					t.Errorf("gave up after %s, want %s", d, timeout)
+				}
+			}
+		})
+	}
+}
+
+func TestServeUntilServeError(t *testing.T) {
+	a := &App{}
+	noShutdown := func(context.Context) error {
+		t.Error("shutdown called after serve returned")
+		return nil
+	}
+	closed := func() error { return http.ErrServerClosed }
+	if err := a.serveUntil(context.Background(), closed, noShutdown); err != nil {
+		t.Errorf("closed server: %v", err)
+	}
+	boom := errors.New("listen: address in use")
+	failed := func() error { return boom }
+	if err := a.serveUntil(context.Background(), failed, noShutdown); !errors.Is(err, boom) {
+		t.Errorf("failed server: %v", err)
+	}
+}
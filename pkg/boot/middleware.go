@@ -0,0 +1,129 @@
+package boot
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/idempotency"
+	"github.com/youbuwei/doeot-go/pkg/ratelimit"
+)
+
+// RegisterMiddleware registers a named middleware for @Middleware /
+// biz.WithMiddleware. Routes naming an unregistered middleware make Run fail.
+func (a *App) RegisterMiddleware(name string, mw biz.Middleware) {
+	if a.middleware == nil {
+		a.middleware = make(middlewareRegistry)
+	}
+	a.middleware[name] = mw
+}
+
+// middlewareRegistry maps names to middleware.
+type middlewareRegistry map[string]biz.Middleware
+
+// wrap applies the middleware named by meta to h; the first name is the
+// outermost.
+func (r middlewareRegistry) wrap(h biz.HandlerFunc, meta *biz.RouteMeta) (biz.HandlerFunc, error) {
+	for i := len(meta.Middleware) - 1; i >= 0; i-- {
+		name := meta.Middleware[i]
+		mw, ok := r[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown middleware %q", name)
+		}
+		h = mw(h)
+	}
+	return h, nil
+}
+
+// handlerChain builds the per-route wrappers boot puts around a handler.
+// The HTTP and RPC transports share it, so a route behaves the same on both.
+type handlerChain struct {
+	middleware  middlewareRegistry
+	rateLimits  ratelimit.Store
+	idempotency idempotency.Store
+	breakers    *breakerRegistry
+}
+
+func (a *App) handlerChain() *handlerChain {
+	return &handlerChain{
+		middleware:  a.middleware,
+		rateLimits:  a.rateLimits,
+		idempotency: a.idempotency,
+		breakers:    a.breakers,
+	}
+}
+
+// build wraps h for the named route. From the outside in: rate limiting,
+// circuit breaker, idempotency, timeout, then the named middleware.
+// Idempotency sits outside the timeout so that its store calls never run
+// on the route's deadline, and it sees the TIMEOUT a late handler gets.
+// Authentication has already run when the chain is entered.
+func (c *handlerChain) build(route string, h biz.HandlerFunc, meta *biz.RouteMeta) (biz.HandlerFunc, error) {
+	h, err := c.middleware.wrap(h, meta)
+	if err != nil {
+		return nil, err
+	}
+	h = c.timeout(h, meta)
+	if h, err = c.idempotent(route, h, meta); err != nil {
+		return nil, err
+	}
+	if h, err = c.circuitBreaker(route, h, meta); err != nil {
+		return nil, err
+	}
+	return c.rateLimit(route, h, meta)
+}
+
+// contextAs finds the transport context implementing T under ctx.
+// Middleware may pass a wrapping Context down the chain, so anything
+// exposing the original through Unwrap() biz.Context is followed.
+func contextAs[T any](ctx biz.Context) (T, bool) {
+	for {
+		if t, ok := ctx.(T); ok {
+			return t, true
+		}
+		u, ok := ctx.(interface{ Unwrap() biz.Context })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		ctx = u.Unwrap()
+	}
+}
+
+// resultContext calls observe with the first outcome passed to Result,
+// before it is written.
+type resultContext struct {
+	biz.Context
+	observe  func(data any, err error)
+	observed bool
+}
+
+func observeResult(ctx biz.Context, observe func(data any, err error)) *resultContext {
+	return &resultContext{Context: ctx, observe: observe}
+}
+
+func (c *resultContext) Unwrap() biz.Context {
+	return c.Context
+}
+
+func (c *resultContext) Result(data any, err error) error {
+	if !c.observed {
+		c.observed = true
+		c.observe(data, err)
+	}
+	return c.Context.Result(data, err)
+}
+
+// routeErrors collects route registration failures so Run can refuse to
+// start instead of serving a route without its middleware.
+type routeErrors struct {
+	list []error
+}
+
+func (r *routeErrors) add(route string, err error) {
+	r.list = append(r.list, fmt.Errorf("boot: %s: %w", route, err))
+}
+
+func (r *routeErrors) err() error {
+	return errors.Join(r.list...)
+}
@@ -0,0 +1,224 @@
+package boot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/config"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+	"github.com/youbuwei/doeot-go/pkg/idempotency"
+	"github.com/youbuwei/doeot-go/pkg/ratelimit"
+)
+
+// traceLog records which layers of the chain a request went through.
+type traceLog []string
+
+func (l *traceLog) add(s string) {
+	*l = append(*l, s)
+}
+
+type traceRateLimits struct {
+	ratelimit.Store
+	log *traceLog
+}
+
+func (s traceRateLimits) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Decision, error) {
+	s.log.add("rateLimit")
+	return s.Store.Allow(ctx, key, limit)
+}
+
+type traceIdempotency struct {
+	idempotency.Store
+	log *traceLog
+}
+
+func (s traceIdempotency) Begin(ctx context.Context, key, fp string, ttl time.Duration) (*idempotency.Record, error) {
+	// The store must not run on the route's 1s deadline.
+	if dl, ok := ctx.Deadline(); ok && time.Until(dl) <= time.Second {
+		s.log.add("idempotent on the route deadline")
+	} else {
+		s.log.add("idempotent")
+	}
+	return s.Store.Begin(ctx, key, fp, ttl)
+}
+
+func TestChainOrder(t *testing.T) {
+	var log traceLog
+	chain := &handlerChain{
+		middleware: middlewareRegistry{"trace": func(next biz.HandlerFunc) biz.HandlerFunc {
+			return func(ctx biz.Context) error {
+				if _, ok := ctx.RequestContext().Deadline(); ok {
+					log.add("middleware")
+				} else {
+					log.add("middleware without deadline")
+				}
+				return next(ctx)
+			}
+		}},
+		rateLimits:  traceRateLimits{ratelimit.NewMemoryStore(), &log},
+		idempotency: traceIdempotency{idempotency.NewMemoryStore(), &log},
+		breakers:    newBreakerRegistry(),
+	}
+	e, r := newTestRouter(chain)
+	r.POST("/pays", func(ctx biz.Context) error {
+		log.add("handler")
+		return ctx.Result(nil, errs.Internal("gateway down"))
+	},
+		biz.WithBizTag("pay.create"),
+		biz.WithRateLimit(1, time.Hour, 2, biz.RateLimitGlobal),
+		biz.WithCircuitBreaker(biz.CircuitBreaker{FailureRatio: 1, Window: time.Minute, MinRequests: 1, Cooldown: time.Hour}),
+		biz.WithIdempotency(time.Hour),
+		biz.WithTimeout(time.Second),
+		biz.WithMiddleware("trace"),
+	)
+	if err := r.errs.err(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		key    string
+		status int
+		log    traceLog
+	}{
+		// Everything runs; the failure opens the breaker.
+		{"k1", http.StatusInternalServerError, traceLog{"rateLimit", "idempotent", "middleware", "handler"}},
+		// The open breaker answers before idempotency.
+		{"k2", http.StatusServiceUnavailable, traceLog{"rateLimit"}},
+		// The rate limit answers before the breaker.
+		{"k3", http.StatusTooManyRequests, traceLog{"rateLimit"}},
+	}
+	for _, tc := range cases {
+		log = nil
+		rec := postWithKey(context.Background(), e, "/pays", tc.key)
+		if rec.Code != tc.status || !reflect.DeepEqual(log, tc.log) {
+			t.Errorf("%s: status %d, chain %q; want %d, %q", tc.key, rec.Code, log, tc.status, tc.log)
+		}
+	}
+}
+
+func TestMiddlewareSharedByTransports(t *testing.T) {
+	chain := &handlerChain{middleware: middlewareRegistry{
+		"tenant": func(next biz.HandlerFunc) biz.HandlerFunc {
+			return func(ctx biz.Context) error {
+				tenant := ctx.Header("X-Tenant")
+				if tenant == "" {
+					return ctx.Result(nil, errs.Forbidden("tenant required"))
+				}
+				ctx.Set("tenant", tenant)
+				return next(ctx)
+			}
+		},
+	}}
+	e, r := newTestRouter(chain)
+	s := newRPCServer("test", config.RPCConfig{}, authRegistry{}, chain)
+	r.GET("/tenant", func(ctx biz.Context) error {
+		return ctx.Result(ctx.Get("tenant"), nil)
+	}, biz.WithMiddleware("tenant"))
+	(&rpcRouter{srv: s}).Handle("tenant.get", func(ctx biz.Context, _ json.RawMessage) (any, error) {
+		return ctx.Get("tenant"), nil
+	}, biz.WithMiddleware("tenant"))
+
+	for _, tenant := range []string{"acme", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/tenant", nil)
+		req.Header.Set("X-Tenant", tenant)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		body := `{"jsonrpc":"2.0","method":"tenant.get","id":1}`
+		rpcReq := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		rpcReq.Header.Set("X-Tenant", tenant)
+		rpcRec := httptest.NewRecorder()
+		s.handle(rpcRec, rpcReq)
+		var reply rpcReply
+		if err := json.Unmarshal(rpcRec.Body.Bytes(), &reply); err != nil {
+			t.Fatal(err)
+		}
+
+		if tenant != "" {
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"data":"acme"`) {
+				t.Errorf("HTTP: status %d, body %s", rec.Code, rec.Body)
+			}
+			if reply.Error != nil || string(reply.Result) != `"acme"` {
+				t.Errorf("RPC: %s", rpcRec.Body)
+			}
+			continue
+		}
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("HTTP without tenant: status %d, body %s", rec.Code, rec.Body)
+		}
+		if reply.Error == nil || reply.Error.Code != errs.RPCCode(errs.CodeForbidden) {
+			t.Errorf("RPC without tenant: %s", rpcRec.Body)
+		}
+	}
+}
+
+// routesModule registers routes through funcs.
+type routesModule struct {
+	http func(biz.Router)
+	rpc  func(biz.RPCRouter)
+}
+
+func (m routesModule) Name() string {
+	return "routes"
+}
+
+func (m routesModule) RegisterHTTP(r biz.Router) {
+	if m.http != nil {
+		m.http(r)
+	}
+}
+
+func (m routesModule) RegisterRPC(r biz.RPCRouter) {
+	if m.rpc != nil {
+		m.rpc(r)
+	}
+}
+
+func TestUnknownMiddlewareFailsStartup(t *testing.T) {
+	ok := func(ctx biz.Context) error { return ctx.Result(nil, nil) }
+	cases := []struct {
+		name string
+		cfg  config.AppConfig
+		mod  routesModule
+		want string
+	}{
+		{
+			name: "HTTP",
+			cfg:  config.AppConfig{HTTP: config.HTTPConfig{Addr: "127.0.0.1:0"}},
+			mod: routesModule{http: func(r biz.Router) {
+				r.GET("/orders", ok)
+				r.Group("/admin", biz.WithMiddleware("audit")).GET("/orders", ok)
+			}},
+			want: `boot: GET /admin/orders: unknown middleware "audit"`,
+		},
+		{
+			name: "RPC",
+			cfg:  config.AppConfig{RPC: config.RPCConfig{Addr: "127.0.0.1:0"}},
+			mod: routesModule{rpc: func(r biz.RPCRouter) {
+				r.Handle("order.list", func(biz.Context, json.RawMessage) (any, error) {
+					return nil, nil
+				}, biz.WithMiddleware("audit"))
+			}},
+			want: `boot: rpc order.list: unknown middleware "audit"`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &App{name: "test", cfg: tc.cfg, breakers: newBreakerRegistry()}
+			a.RegisterModule(tc.mod)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			err := a.serve(ctx)
+			if err == nil || err.Error() != tc.want {
+				t.Fatalf("serve: %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
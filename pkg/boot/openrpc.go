@@ -0,0 +1,171 @@
+package boot
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+)
+
+// rpcDiscoverMethod is the OpenRPC service discovery method.
+const rpcDiscoverMethod = "rpc.discover"
+
+// openRPCVersion is the OpenRPC specification version of the discover document.
+const openRPCVersion = "1.3.2"
+
+// registerDiscover adds the built-in OpenRPC discovery method. Like
+// /openapi.json it is public and only served when Docs.Enabled is set.
+func (s *rpcServer) registerDiscover() {
+	s.handlers[rpcDiscoverMethod] = rpcMethod{
+		h:    adaptRPC(s.discover),
+		meta: &biz.RouteMeta{BizTag: rpcDiscoverMethod, Desc: "OpenRPC service discovery"},
+	}
+}
+
+// discover returns an OpenRPC document describing every registered method.
+// Handlers are fixed once the server starts, so the document is built once.
+func (s *rpcServer) discover(biz.Context, json.RawMessage) (any, error) {
+	s.discoverOnce.Do(func() {
+		s.discoverDoc = buildOpenRPC(s.name, s.handlers)
+	})
+	return s.discoverDoc, nil
+}
+
+// buildOpenRPC assembles the document from RouteMeta. The per-method schemas
+// are self-contained; their "$defs" are hoisted into components.schemas so
+// shared types appear once (names are module-prefixed by bizgen).
+func buildOpenRPC(name string, handlers map[string]rpcMethod) map[string]any {
+	names := make([]string, 0, len(handlers))
+	for n := range handlers {
+		// rpc.* methods are built in (discovery, introspection).
+		if !strings.HasPrefix(n, "rpc.") {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	schemas := map[string]any{}
+	methods := make([]any, 0, len(names))
+	for _, n := range names {
+		meta := handlers[n].meta
+		m := map[string]any{
+			"name":           n,
+			"paramStructure": "by-name",
+			"params":         openRPCParams(meta.ParamsSchema, schemas),
+			"result": map[string]any{
+				"name":   "result",
+				"schema": openRPCSchema(meta.ResultSchema, schemas),
+			},
+		}
+		if meta.Desc != "" {
+			m["summary"] = meta.Desc
+		}
+		if len(meta.Tags) > 0 {
+			tags := make([]any, 0, len(meta.Tags))
+			for _, t := range meta.Tags {
+				tags = append(tags, map[string]any{"name": t})
+			}
+			m["tags"] = tags
+		}
+		// RouteMeta fields without an OpenRPC counterpart use extensions.
+		if meta.Auth != "" {
+			m["x-auth"] = meta.Auth
+		}
+		if meta.BizTag != "" {
+			m["x-biz-tag"] = meta.BizTag
+		}
+		methods = append(methods, m)
+	}
+
+	if name == "" {
+		name = "doeot"
+	}
+	return map[string]any{
+		"openrpc": openRPCVersion,
+		"info": map[string]any{
+			"title":   name,
+			"version": "0.0.0",
+		},
+		"methods":    methods,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+// openRPCParams turns the params object schema into one content descriptor
+// per property, matching the by-name param structure the wrappers decode.
+func openRPCParams(raw json.RawMessage, schemas map[string]any) []any {
+	root := openRPCSchema(raw, schemas)
+	if ref, ok := root["$ref"].(string); ok {
+		if def, ok := schemas[strings.TrimPrefix(ref, openRPCRefPrefix)].(map[string]any); ok {
+			root = def
+		}
+	}
+	props, _ := root["properties"].(map[string]any)
+	required := map[string]bool{}
+	if req, ok := root["required"].([]any); ok {
+		for _, r := range req {
+			if s, ok := r.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := make([]any, 0, len(keys))
+	for _, k := range keys {
+		p := map[string]any{
+			"name":     k,
+			"schema":   props[k],
+			"required": required[k],
+		}
+		if s, ok := props[k].(map[string]any); ok {
+			if desc, ok := s["description"].(string); ok {
+				p["description"] = desc
+			}
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+const openRPCRefPrefix = "#/components/schemas/"
+
+// openRPCSchema decodes a bizgen schema, moves its "$defs" into schemas and
+// rewrites "#/$defs/" references accordingly. A missing schema is "any".
+func openRPCSchema(raw json.RawMessage, schemas map[string]any) map[string]any {
+	s := map[string]any{}
+	if len(raw) == 0 || json.Unmarshal(raw, &s) != nil {
+		return map[string]any{}
+	}
+	if defs, ok := s["$defs"].(map[string]any); ok {
+		delete(s, "$defs")
+		for k, v := range defs {
+			schemas[k] = rewriteRefs(v)
+		}
+	}
+	return rewriteRefs(s).(map[string]any)
+}
+
+func rewriteRefs(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if ref, ok := val.(string); ok && k == "$ref" {
+				t[k] = strings.Replace(ref, "#/$defs/", openRPCRefPrefix, 1)
+				continue
+			}
+			t[k] = rewriteRefs(val)
+		}
+	case []any:
+		for i := range t {
+			t[i] = rewriteRefs(t[i])
+		}
+	}
+	return v
+}
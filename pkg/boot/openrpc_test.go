@@ -0,0 +1,23 @@
+package boot
+
+import (
+	"testing"
+
+	"github.com/youbuwei/doeot-go/pkg/config"
+)
+
+func TestDiscoverFollowsDocsEnabled(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		a := &App{name: "test", breakers: newBreakerRegistry()}
+		a.cfg.RPC = config.RPCConfig{Addr: ":0", BatchConcurrency: 1}
+		a.cfg.Docs.Enabled = enabled
+
+		srv, err := a.buildRPC()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := srv.handlers[rpcDiscoverMethod]; ok != enabled {
+			t.Errorf("docs.enabled=%v: %s registered = %v", enabled, rpcDiscoverMethod, ok)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package boot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+	"github.com/youbuwei/doeot-go/pkg/ratelimit"
+)
+
+// SetRateLimitStore replaces the in-process token buckets behind @RateLimit,
+// e.g. with a store shared by every replica. It must be called before Run.
+func (a *App) SetRateLimitStore(s ratelimit.Store) {
+	a.rateLimits = s
+}
+
+// rateLimit wraps h with the route's @RateLimit. Buckets belong to the
+// BizTag, like circuit breakers, so the HTTP route and RPC method of an
+// endpoint share one budget; they are partitioned by the limit key. When
+// the store fails the call is let through and the failure logged: an
+// unavailable shared backend must not take the service down.
+func (c *handlerChain) rateLimit(route string, h biz.HandlerFunc, meta *biz.RouteMeta) (biz.HandlerFunc, error) {
+	rl := meta.RateLimit
+	if rl == nil {
+		return h, nil
+	}
+	limit := ratelimit.Limit{Count: rl.Limit, Period: rl.Period, Burst: rl.Burst}
+	if !limit.Valid() {
+		return nil, fmt.Errorf("invalid rate limit %d/%s burst=%d", rl.Limit, rl.Period, rl.Burst)
+	}
+	switch rl.Key {
+	case biz.RateLimitByIP, biz.RateLimitByUser, biz.RateLimitGlobal:
+	default:
+		return nil, fmt.Errorf("unknown rate limit key %q", rl.Key)
+	}
+	if c.rateLimits == nil {
+		return nil, errors.New("rate limit store not configured")
+	}
+	name := meta.BizTag
+	if name == "" {
+		name = route
+	}
+
+	return func(ctx biz.Context) error {
+		key := name + "|" + rateLimitKey(ctx, rl.Key)
+		d, err := c.rateLimits.Allow(ctx.RequestContext(), key, limit)
+		if err != nil {
+			log.Printf("boot: rate limit %s: store failed, call let through: request_id=%s err=%v", name, ctx.RequestID(), err)
+			return h(ctx)
+		}
+		if !d.Allowed {
+			return ctx.Result(nil, errs.TooManyRequests(fmt.Sprintf(
+				"rate limit exceeded, retry after %ds", int(math.Ceil(d.RetryAfter.Seconds())))).
+				WithRetry(d.RetryAfter))
+		}
+		return h(ctx)
+	}, nil
+}
+
+// rateLimitKey identifies the caller's bucket within a BizTag.
+func rateLimitKey(ctx biz.Context, key string) string {
+	switch key {
+	case biz.RateLimitGlobal:
+		return "*"
+	case biz.RateLimitByUser:
+		if p := ctx.Principal(); p != nil && p.ID != "" {
+			return "user:" + p.ID
+		}
+	}
+	return "ip:" + ctx.RemoteAddr()
+}
@@ -0,0 +1,139 @@
+package boot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/config"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+	"github.com/youbuwei/doeot-go/pkg/ratelimit"
+)
+
+func TestRateLimitByIPIgnoresSpoofedHeaders(t *testing.T) {
+	cases := []struct {
+		name    string
+		trusted []string
+		from    string
+		headers []map[string]string
+		want    []int
+	}{
+		{
+			name: "no trusted proxies",
+			from: "203.0.113.7:4000",
+			headers: []map[string]string{
+				nil,
+				{"X-Forwarded-For": "198.51.100.1"},
+				{"X-Real-IP": "198.51.100.2"},
+			},
+			want: []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests},
+		},
+		{
+			name:    "untrusted peer",
+			trusted: []string{"10.0.0.0/8"},
+			from:    "203.0.113.7:4000",
+			headers: []map[string]string{
+				{"X-Forwarded-For": "198.51.100.1"},
+				{"X-Forwarded-For": "198.51.100.2"},
+			},
+			want: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:    "trusted proxy",
+			trusted: []string{"10.0.0.0/8"},
+			from:    "10.1.2.3:4000",
+			headers: []map[string]string{
+				{"X-Forwarded-For": "198.51.100.1"},
+				{"X-Forwarded-For": "198.51.100.2"},
+				{"X-Real-IP": "198.51.100.1"},
+				// A spoofed entry in front of the proxy's does not help.
+				{"X-Forwarded-For": "192.0.2.9, 198.51.100.2"},
+			},
+			want: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, r := newTestRouter(&handlerChain{rateLimits: ratelimit.NewMemoryStore()})
+			e.IPExtractor = clientIPExtractor(tc.trusted)
+			r.GET("/sms", func(ctx biz.Context) error {
+				return ctx.Result(ctx.RemoteAddr(), nil)
+			}, biz.WithRateLimit(1, time.Minute, 1, biz.RateLimitByIP))
+			if err := r.errs.err(); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, h := range tc.headers {
+				req := httptest.NewRequest(http.MethodGet, "/sms", nil)
+				req.RemoteAddr = tc.from
+				for k, v := range h {
+					req.Header.Set(k, v)
+				}
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				if rec.Code != tc.want[i] {
+					t.Errorf("request %d %v: status %d, want %d", i, h, rec.Code, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitSharedByTransports(t *testing.T) {
+	chain := &handlerChain{rateLimits: ratelimit.NewMemoryStore()}
+	e, r := newTestRouter(chain)
+	s := newRPCServer("test", config.RPCConfig{}, authRegistry{}, chain)
+	opts := []biz.RouteOption{biz.WithBizTag("sms.send"), biz.WithRateLimit(1, time.Minute, 1, biz.RateLimitGlobal)}
+	r.POST("/sms", func(ctx biz.Context) error {
+		return ctx.Result(nil, nil)
+	}, opts...)
+	(&rpcRouter{srv: s}).Handle("sms.send", func(biz.Context, json.RawMessage) (any, error) {
+		return nil, nil
+	}, opts...)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sms", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("HTTP: status %d", rec.Code)
+	}
+	if reply := callRPC(t, s, "sms.send", ""); reply.Error == nil || reply.Error.Code != errs.RPCCode(errs.CodeTooManyRequests) {
+		t.Fatalf("RPC after the budget was used over HTTP: %+v", reply)
+	}
+}
+
+type failingRateLimits struct{}
+
+func (failingRateLimits) Allow(context.Context, string, ratelimit.Limit) (ratelimit.Decision, error) {
+	return ratelimit.Decision{}, errors.New("redis: connection refused")
+}
+
+func TestRateLimitStoreFailureLetsCallsThrough(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	e, r := newTestRouter(&handlerChain{rateLimits: failingRateLimits{}})
+	r.POST("/sms", func(ctx biz.Context) error {
+		return ctx.Result(nil, nil)
+	}, biz.WithBizTag("sms.send"), biz.WithRateLimit(1, time.Minute, 1, biz.RateLimitGlobal))
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sms", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("call %d: status %d", i, rec.Code)
+		}
+	}
+	if n := strings.Count(logs.String(), "rate limit sms.send: store failed"); n != 2 {
+		t.Errorf("logged %d store failures, want 2:\n%s", n, logs.String())
+	}
+}
@@ -0,0 +1,106 @@
+package boot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/config"
+	"github.com/youbuwei/doeot-go/pkg/jsonrpc"
+)
+
+var generatedID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+func TestRequestIDPropagation(t *testing.T) {
+	// The downstream service reports the request ID its handler saw.
+	chain := &handlerChain{}
+	s := newRPCServer("downstream", config.RPCConfig{}, authRegistry{}, chain)
+	(&rpcRouter{srv: s}).Handle("trace.id", func(ctx biz.Context, _ json.RawMessage) (any, error) {
+		return ctx.RequestID(), nil
+	})
+	downstream := httptest.NewServer(s.handler())
+	defer downstream.Close()
+	client := jsonrpc.NewClient(downstream.URL + "/")
+
+	e, r := newTestRouter(chain)
+	e.Use(requestIDMiddleware)
+	r.GET("/trace", func(ctx biz.Context) error {
+		var remote string
+		err := client.Call(ctx.RequestContext(), "trace.id", nil, &remote)
+		return ctx.Result(map[string]string{"local": ctx.RequestID(), "remote": remote}, err)
+	})
+
+	cases := []struct {
+		name string
+		id   string
+		keep bool
+	}{
+		{"valid", "order-42_retry.1", true},
+		{"max length", strings.Repeat("a", biz.MaxRequestIDLen), true},
+		{"missing", "", false},
+		{"too long", strings.Repeat("a", biz.MaxRequestIDLen+1), false},
+		{"space", "order 42", false},
+		{"log injection", "42\" level=error msg=\"forged", false},
+		{"non-ASCII", "订单-42", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/trace", nil)
+			if tc.id != "" {
+				req.Header.Set(biz.HeaderRequestID, tc.id)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			var body struct {
+				Data struct{ Local, Remote string }
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK {
+				t.Fatalf("status %d, body %s", rec.Code, rec.Body)
+			}
+			id := rec.Header().Get(biz.HeaderRequestID)
+			switch {
+			case tc.keep && id != tc.id:
+				t.Errorf("response ID %q, want the caller's", id)
+			case !tc.keep && !generatedID.MatchString(id):
+				t.Errorf("response ID %q, want a generated one", id)
+			}
+			if body.Data.Local != id || body.Data.Remote != id {
+				t.Errorf("response ID %q, handler saw %q, downstream saw %q", id, body.Data.Local, body.Data.Remote)
+			}
+		})
+	}
+}
+
+func TestRPCRequestID(t *testing.T) {
+	s := newRPCServer("test", config.RPCConfig{}, authRegistry{}, &handlerChain{})
+	for _, tc := range []struct {
+		id   string
+		keep bool
+	}{
+		{"rpc-1", true},
+		{strings.Repeat("x", biz.MaxRequestIDLen+1), false},
+		{"a/b", false},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","method":"nope","id":1}`))
+		req.Header.Set(biz.HeaderRequestID, tc.id)
+		rec := httptest.NewRecorder()
+		s.handle(rec, req)
+
+		var reply rpcReply
+		if err := json.Unmarshal(rec.Body.Bytes(), &reply); err != nil || reply.Error == nil {
+			t.Fatalf("response %s", rec.Body)
+		}
+		id := rec.Header().Get(biz.HeaderRequestID)
+		if tc.keep != (id == tc.id) || (!tc.keep && !generatedID.MatchString(id)) {
+			t.Errorf("%q: response ID %q", tc.id, id)
+		}
+		if reply.Error.Data.RequestID != id {
+			t.Errorf("%q: error.data.request_id %q, header %q", tc.id, reply.Error.Data.RequestID, id)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package boot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/breaker"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+)
+
+// Introspection endpoints mounted when Debug.Enabled is set.
+const (
+	breakersPath      = "/debug/breakers"
+	rpcBreakersMethod = "rpc.breakers"
+)
+
+// timeout wraps h with the route's @Timeout.
+func (c *handlerChain) timeout(h biz.HandlerFunc, meta *biz.RouteMeta) biz.HandlerFunc {
+	d := meta.Timeout
+	if d <= 0 {
+		return h
+	}
+	return func(ctx biz.Context) error {
+		tctx, cancel := context.WithTimeout(ctx.RequestContext(), d)
+		defer cancel()
+		return h(&timeoutContext{Context: ctx, ctx: tctx, msg: fmt.Sprintf("request timed out after %s", d)})
+	}
+}
+
+// timeoutContext carries the deadline of a @Timeout route and reports
+// failures caused by it as errs.Timeout.
+type timeoutContext struct {
+	biz.Context
+	ctx context.Context
+	msg string
+}
+
+func (c *timeoutContext) Unwrap() biz.Context {
+	return c.Context
+}
+
+func (c *timeoutContext) RequestContext() context.Context {
+	return c.ctx
+}
+
+// Result keeps business errors (NotFound, ...) but replaces any other error
+// returned after the deadline passed.
+func (c *timeoutContext) Result(data any, err error) error {
+	if err != nil && errors.Is(c.ctx.Err(), context.DeadlineExceeded) {
+		var e *errs.Error
+		if !errors.As(err, &e) || e.Code == errs.CodeInternal {
+			err = errs.Timeout(c.msg).WithCause(err)
+		}
+	}
+	return c.Context.Result(data, err)
+}
+
+// circuitBreaker wraps h with the @CircuitBreaker of its BizTag. HTTP routes
+// and RPC methods of the same endpoint share one breaker.
+func (c *handlerChain) circuitBreaker(route string, h biz.HandlerFunc, meta *biz.RouteMeta) (biz.HandlerFunc, error) {
+	cb := meta.CircuitBreaker
+	if cb == nil {
+		return h, nil
+	}
+	cfg := breaker.Config{
+		FailureRatio: cb.FailureRatio,
+		Window:       cb.Window,
+		MinRequests:  cb.MinRequests,
+		Cooldown:     cb.Cooldown,
+	}
+	if !cfg.Valid() {
+		return nil, fmt.Errorf("invalid circuit breaker failureRatio=%g window=%s", cb.FailureRatio, cb.Window)
+	}
+	if c.breakers == nil {
+		return nil, errors.New("circuit breakers not configured")
+	}
+	name := meta.BizTag
+	if name == "" {
+		name = route
+	}
+	b, err := c.breakers.get(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx biz.Context) (err error) {
+		gen, ok := b.Allow()
+		if !ok {
+			return ctx.Result(nil, errs.Unavailable("service temporarily unavailable"))
+		}
+
+		var (
+			observed bool
+			failed   bool
+		)
+		defer func() {
+			if p := recover(); p != nil {
+				b.Done(gen, false)
+				panic(p)
+			}
+			if !observed {
+				failed = isServerFailure(err)
+			}
+			b.Done(gen, !failed)
+		}()
+		return h(observeResult(ctx, func(_ any, err error) {
+			observed, failed = true, isServerFailure(err)
+		}))
+	}, nil
+}
+
+// isServerFailure reports whether err counts against a circuit breaker.
+// Client errors (bad request, not found, ...) say nothing about the health
+// of the endpoint.
+func isServerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e *errs.Error
+	if !errors.As(err, &e) {
+		return true
+	}
+	return errs.HTTPStatus(e.Code) >= http.StatusInternalServerError
+}
+
+// breakerRegistry holds one breaker per BizTag, shared by the transports.
+type breakerRegistry struct {
+	now func() time.Time
+
+	mu   sync.Mutex
+	m    map[string]*breaker.Breaker
+	cfgs map[string]breaker.Config
+}
+
+func newBreakerRegistry() *breakerRegistry {
+	return &breakerRegistry{now: time.Now, m: make(map[string]*breaker.Breaker), cfgs: make(map[string]breaker.Config)}
+}
+
+// get returns the breaker for name, creating it with cfg on first use.
+// Routes sharing a breaker must agree on its settings.
+func (r *breakerRegistry) get(name string, cfg breaker.Config) (*breaker.Breaker, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.m[name]
+	if !ok {
+		b = breaker.NewWithClock(name, cfg, r.now)
+		r.m[name] = b
+		r.cfgs[name] = cfg
+	} else if r.cfgs[name] != cfg {
+		return nil, fmt.Errorf("circuit breaker %q is already configured with different settings", name)
+	}
+	return b, nil
+}
+
+// snapshots returns the state of every breaker, sorted by name.
+func (r *breakerRegistry) snapshots() []breaker.Snapshot {
+	r.mu.Lock()
+	list := make([]*breaker.Breaker, 0, len(r.m))
+	for _, b := range r.m {
+		list = append(list, b)
+	}
+	r.mu.Unlock()
+
+	out := make([]breaker.Snapshot, 0, len(list))
+	for _, b := range list {
+		out = append(out, b.Snapshot())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// mountBreakers serves the breaker states over HTTP.
+func mountBreakers(e *echo.Echo, r *breakerRegistry) {
+	e.GET(breakersPath, func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]any{
+			"code": errs.CodeOK,
+			"data": r.snapshots(),
+		})
+	})
+}
+
+// registerBreakers exposes the breaker states as a built-in RPC method.
+func (s *rpcServer) registerBreakers(r *breakerRegistry) {
+	s.handlers[rpcBreakersMethod] = rpcMethod{
+		h: adaptRPC(func(biz.Context, json.RawMessage) (any, error) {
+			return r.snapshots(), nil
+		}),
+		meta: &biz.RouteMeta{BizTag: rpcBreakersMethod, Desc: "Circuit breaker states"},
+	}
+}
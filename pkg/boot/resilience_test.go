@@ -0,0 +1,209 @@
+package boot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/breaker"
+	"github.com/youbuwei/doeot-go/pkg/config"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+)
+
+func TestCircuitBreakerConfigConflict(t *testing.T) {
+	chain := &handlerChain{breakers: newBreakerRegistry()}
+	_, r := newTestRouter(chain)
+	s := newRPCServer("test", config.RPCConfig{}, authRegistry{}, chain)
+
+	cb := biz.CircuitBreaker{FailureRatio: 0.5, Window: time.Minute}
+	ok := func(ctx biz.Context) error { return ctx.Result(nil, nil) }
+	r.POST("/orders", ok, biz.WithBizTag("order.create"), biz.WithCircuitBreaker(cb))
+	(&rpcRouter{srv: s}).Handle("order.create", func(biz.Context, json.RawMessage) (any, error) {
+		return nil, nil
+	}, biz.WithBizTag("order.create"), biz.WithCircuitBreaker(cb))
+	if err := r.errs.err(); err != nil {
+		t.Fatalf("same settings: %v", err)
+	}
+	if err := s.errs.err(); err != nil {
+		t.Fatalf("same settings over RPC: %v", err)
+	}
+
+	cb.FailureRatio = 0.8
+	r.PUT("/orders", ok, biz.WithBizTag("order.create"), biz.WithCircuitBreaker(cb))
+	err := r.errs.err()
+	if err == nil || !strings.Contains(err.Error(), `circuit breaker "order.create"`) {
+		t.Fatalf("different settings: err = %v", err)
+	}
+}
+
+// fakeClock is a manually advanced clock for breakers.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	reg := newBreakerRegistry()
+	reg.now = clock.Now
+	chain := &handlerChain{breakers: reg}
+	e, r := newTestRouter(chain)
+	s := newRPCServer("test", config.RPCConfig{}, authRegistry{}, chain)
+
+	var (
+		next  error
+		calls int
+	)
+	opts := []biz.RouteOption{
+		biz.WithBizTag("pay.create"),
+		biz.WithCircuitBreaker(biz.CircuitBreaker{FailureRatio: 0.5, Window: 10 * time.Second, MinRequests: 4, Cooldown: 5 * time.Second}),
+	}
+	r.POST("/pays", func(ctx biz.Context) error {
+		calls++
+		return ctx.Result("paid", next)
+	}, opts...)
+	(&rpcRouter{srv: s}).Handle("pay.create", func(biz.Context, json.RawMessage) (any, error) {
+		calls++
+		return "paid", next
+	}, opts...)
+	if err := errors.Join(r.errs.err(), s.errs.err()); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(err error) int {
+		t.Helper()
+		next = err
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/pays", nil))
+		return rec.Code
+	}
+	state := func() breaker.Snapshot {
+		t.Helper()
+		snaps := reg.snapshots()
+		if len(snaps) != 1 {
+			t.Fatalf("breakers %+v, want one for pay.create", snaps)
+		}
+		return snaps[0]
+	}
+
+	// Client errors do not count; 3 failures in 6 calls trip the breaker.
+	for i, err := range []error{nil, errs.NotFound("no such order"), nil, errs.Internal("db down"), errs.Internal("db down")} {
+		if post(err); state().State != breaker.Closed {
+			t.Fatalf("call %d: breaker %+v, want closed", i, state())
+		}
+	}
+	if code := post(errors.New("db down")); code != http.StatusInternalServerError || state().State != breaker.Open {
+		t.Fatalf("tripping call: status %d, breaker %+v", code, state())
+	}
+
+	// Open: both transports fail fast without calling the handler.
+	calls = 0
+	if code := post(nil); code != http.StatusServiceUnavailable {
+		t.Fatalf("open: status %d, want 503", code)
+	}
+	if reply := callRPC(t, s, "pay.create", ""); reply.Error == nil || reply.Error.Code != errs.RPCCode(errs.CodeUnavailable) {
+		t.Fatalf("open over RPC: %+v", reply)
+	}
+	if calls != 0 {
+		t.Fatalf("open breaker called the handler %d times", calls)
+	}
+
+	// After the cooldown one probe runs; a failed probe reopens.
+	clock.Advance(5 * time.Second)
+	if st := state().State; st != breaker.HalfOpen {
+		t.Fatalf("after cooldown: %s, want half-open", st)
+	}
+	if code := post(errs.Internal("db down")); code != http.StatusInternalServerError || state().State != breaker.Open {
+		t.Fatalf("failed probe: status %d, breaker %+v", code, state())
+	}
+	if code := post(nil); code != http.StatusServiceUnavailable {
+		t.Fatalf("reopened: status %d, want 503", code)
+	}
+
+	// A successful probe closes it with an empty window.
+	clock.Advance(5 * time.Second)
+	if code := post(nil); code != http.StatusOK {
+		t.Fatalf("probe: status %d", code)
+	}
+	if st := state(); st.State != breaker.Closed || st.Requests != 0 {
+		t.Fatalf("after probe: %+v, want closed and empty", st)
+	}
+
+	// Outcomes leave the sliding window once it has passed.
+	for i := 0; i < 3; i++ {
+		post(errs.Internal("db down"))
+	}
+	clock.Advance(11 * time.Second)
+	if post(errs.Internal("db down")); state().State != breaker.Closed || state().Requests != 1 {
+		t.Fatalf("after the window: %+v, want closed with 1 request", state())
+	}
+	clock.Advance(5 * time.Second)
+	for i := 0; i < 3; i++ {
+		post(errs.Internal("db down"))
+	}
+	if st := state(); st.State != breaker.Open {
+		t.Fatalf("4 failures within the window: %+v, want open", st)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	chain := &handlerChain{}
+	e, r := newTestRouter(chain)
+	s := newRPCServer("test", config.RPCConfig{}, authRegistry{}, chain)
+
+	slow := func(ctx context.Context, notFound bool) error {
+		<-ctx.Done()
+		if notFound {
+			return errs.NotFound("no such order")
+		}
+		return ctx.Err()
+	}
+	r.GET("/orders", func(ctx biz.Context) error {
+		return ctx.Result(nil, slow(ctx.RequestContext(), ctx.Header("X-Order") == "missing"))
+	}, biz.WithTimeout(10*time.Millisecond))
+	(&rpcRouter{srv: s}).Handle("order.get", func(ctx biz.Context, params json.RawMessage) (any, error) {
+		return nil, slow(ctx.RequestContext(), string(params) == `"missing"`)
+	}, biz.WithTimeout(10*time.Millisecond))
+	if err := errors.Join(r.errs.err(), s.errs.err()); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		id   string
+		want errs.Code
+	}{
+		{"1", errs.CodeTimeout},
+		// Business errors returned after the deadline are kept.
+		{"missing", errs.CodeNotFound},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set("X-Order", tc.id)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var body struct {
+			Code errs.Code `json:"code"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != errs.HTTPStatus(tc.want) || body.Code != tc.want {
+			t.Errorf("HTTP %s: status %d, body %s, want %s", tc.id, rec.Code, rec.Body, tc.want)
+		}
+
+		reply := callRPC(t, s, "order.get", `"`+tc.id+`"`)
+		if reply.Error == nil || reply.Error.Code != errs.RPCCode(tc.want) || reply.Error.Data.Code != tc.want {
+			t.Errorf("RPC %s: %+v, want %s", tc.id, reply.Error, tc.want)
+		}
+	}
+}
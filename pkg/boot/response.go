@@ -0,0 +1,176 @@
+package boot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+)
+
+// writeRaw writes the raw response types of biz (and string / []byte /
+// io.Reader data on routes with a non-JSON @Produces). It reports false when
+// data should go through the regular JSON envelope.
+func (ctx *echoContext) writeRaw(data any) (bool, error) {
+	switch v := data.(type) {
+	case biz.FileResponse:
+		return true, ctx.writeFile(&v)
+	case *biz.FileResponse:
+		if v != nil {
+			return true, ctx.writeFile(v)
+		}
+	case biz.Redirect:
+		return true, ctx.redirect(&v)
+	case *biz.Redirect:
+		if v != nil {
+			return true, ctx.redirect(v)
+		}
+	case biz.Stream:
+		return true, ctx.writeStream(&v)
+	case *biz.Stream:
+		if v != nil {
+			return true, ctx.writeStream(v)
+		}
+	}
+
+	produces := ctx.meta.Produces
+	if produces == "" || isJSON(baseMediaType(produces)) {
+		return false, nil
+	}
+	switch v := data.(type) {
+	case []byte:
+		return true, ctx.c.Blob(http.StatusOK, produces, v)
+	case string:
+		return true, ctx.c.Blob(http.StatusOK, produces, []byte(v))
+	case io.Reader:
+		if c, ok := v.(io.Closer); ok {
+			defer c.Close()
+		}
+		return true, ctx.c.Stream(http.StatusOK, produces, v)
+	}
+	return false, nil
+}
+
+func (ctx *echoContext) writeFile(f *biz.FileResponse) error {
+	if c, ok := f.Body.(io.Closer); ok {
+		defer c.Close()
+	}
+	ct := f.ContentType
+	if ct == "" {
+		ct = ctx.meta.Produces
+	}
+	if ct == "" {
+		ct = echo.MIMEOctetStream
+	}
+
+	h := ctx.c.Response().Header()
+	if f.Name != "" || f.Inline {
+		disposition := "attachment"
+		if f.Inline {
+			disposition = "inline"
+		}
+		if f.Name != "" {
+			disposition = mime.FormatMediaType(disposition, map[string]string{"filename": f.Name})
+		}
+		h.Set(echo.HeaderContentDisposition, disposition)
+	}
+	if f.Size > 0 {
+		h.Set(echo.HeaderContentLength, strconv.FormatInt(f.Size, 10))
+	}
+	if f.Body == nil {
+		return ctx.c.Blob(http.StatusOK, ct, nil)
+	}
+	return ctx.c.Stream(http.StatusOK, ct, f.Body)
+}
+
+func (ctx *echoContext) redirect(r *biz.Redirect) error {
+	status := r.Status
+	if status == 0 {
+		status = http.StatusFound
+	}
+	return ctx.c.Redirect(status, r.URL)
+}
+
+// writeStream sends server-sent events, flushing after each one.
+func (ctx *echoContext) writeStream(s *biz.Stream) error {
+	resp := ctx.c.Response()
+	h := resp.Header()
+	h.Set(echo.HeaderContentType, "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	resp.WriteHeader(http.StatusOK)
+	resp.Flush()
+
+	done := ctx.RequestContext().Done()
+	for {
+		select {
+		case <-done:
+			return nil
+		case ev, ok := <-s.Events:
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(resp, ev); err != nil {
+				return err
+			}
+			resp.Flush()
+		}
+	}
+}
+
+func writeEvent(w io.Writer, ev biz.Event) error {
+	var b strings.Builder
+	if ev.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", ev.ID)
+	}
+	if ev.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", ev.Event)
+	}
+	if ev.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", ev.Retry.Milliseconds())
+	}
+
+	var data string
+	switch v := ev.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(raw)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func baseMediaType(ct string) string {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return ct
+	}
+	return mt
+}
+
+// retryAfter formats d as the delay-seconds of a Retry-After header.
+func retryAfter(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,211 @@
+package boot
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+)
+
+// closeReader records whether the response writer closed it.
+type closeReader struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestRawResponses(t *testing.T) {
+	report := &closeReader{Reader: strings.NewReader("id,amount\n1,100\n")}
+	pdf := &closeReader{Reader: strings.NewReader("%PDF-1.7")}
+	csv := biz.WithProduces("text/csv; charset=utf-8")
+
+	cases := []struct {
+		name     string
+		data     any
+		opts     []biz.RouteOption
+		wantCode int
+		wantHdr  map[string]string
+		wantBody string
+	}{
+		{
+			name: "file attachment",
+			data: biz.FileResponse{Name: "report 1.csv", ContentType: "text/csv", Size: 16, Body: report},
+			wantHdr: map[string]string{
+				"Content-Type":        "text/csv",
+				"Content-Disposition": `attachment; filename="report 1.csv"`,
+				"Content-Length":      "16",
+			},
+			wantBody: "id,amount\n1,100\n",
+		},
+		{
+			name: "inline file uses @Produces",
+			data: &biz.FileResponse{Inline: true, Body: pdf},
+			opts: []biz.RouteOption{biz.WithProduces("application/pdf")},
+			wantHdr: map[string]string{
+				"Content-Type":        "application/pdf",
+				"Content-Disposition": "inline",
+			},
+			wantBody: "%PDF-1.7",
+		},
+		{
+			name: "empty file",
+			data: biz.FileResponse{},
+			wantHdr: map[string]string{
+				"Content-Type":        "application/octet-stream",
+				"Content-Disposition": "",
+			},
+		},
+		{
+			name:     "redirect defaults to 302",
+			data:     biz.Redirect{URL: "/login"},
+			wantCode: http.StatusFound,
+			wantHdr:  map[string]string{"Location": "/login"},
+		},
+		{
+			name:     "redirect status",
+			data:     &biz.Redirect{URL: "https://example.com/", Status: http.StatusMovedPermanently},
+			wantCode: http.StatusMovedPermanently,
+			wantHdr:  map[string]string{"Location": "https://example.com/"},
+		},
+		{
+			name:     "string with @Produces",
+			data:     "1,100\n",
+			opts:     []biz.RouteOption{csv},
+			wantHdr:  map[string]string{"Content-Type": "text/csv; charset=utf-8"},
+			wantBody: "1,100\n",
+		},
+		{
+			name:     "bytes with @Produces",
+			data:     []byte("1,100\n"),
+			opts:     []biz.RouteOption{csv},
+			wantHdr:  map[string]string{"Content-Type": "text/csv; charset=utf-8"},
+			wantBody: "1,100\n",
+		},
+		{
+			name:     "reader with @Produces",
+			data:     strings.NewReader("1,100\n"),
+			opts:     []biz.RouteOption{csv},
+			wantHdr:  map[string]string{"Content-Type": "text/csv; charset=utf-8"},
+			wantBody: "1,100\n",
+		},
+		{
+			name:     "string without @Produces",
+			data:     "1,100",
+			wantHdr:  map[string]string{"Content-Type": "application/json"},
+			wantBody: `{"code":"OK","data":"1,100"}` + "\n",
+		},
+		{
+			name:     "JSON @Produces",
+			data:     "1,100",
+			opts:     []biz.RouteOption{biz.WithProduces("application/json")},
+			wantHdr:  map[string]string{"Content-Type": "application/json"},
+			wantBody: `{"code":"OK","data":"1,100"}` + "\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, r := newTestRouter(&handlerChain{})
+			r.GET("/raw", func(ctx biz.Context) error {
+				return ctx.Result(tc.data, nil)
+			}, tc.opts...)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/raw", nil))
+			wantCode := tc.wantCode
+			if wantCode == 0 {
+				wantCode = http.StatusOK
+			}
+			if rec.Code != wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, wantCode, rec.Body)
+			}
+			for k, want := range tc.wantHdr {
+				if got := rec.Header().Get(k); got != want {
+					t.Errorf("%s = %q, want %q", k, got, want)
+				}
+			}
+			if tc.wantBody != "" && rec.Body.String() != tc.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body, tc.wantBody)
+			}
+		})
+	}
+	if !report.closed || !pdf.closed {
+		t.Errorf("file bodies closed: %v, %v", report.closed, pdf.closed)
+	}
+}
+
+func TestStreamResponse(t *testing.T) {
+	e, r := newTestRouter(&handlerChain{})
+	r.GET("/events", func(ctx biz.Context) error {
+		events := make(chan biz.Event, 3)
+		events <- biz.Event{ID: "1", Event: "tick", Data: "one\ntwo", Retry: 1500 * time.Millisecond}
+		events <- biz.Event{Data: map[string]int{"n": 2}}
+		events <- biz.Event{}
+		close(events)
+		return ctx.Result(biz.Stream{Events: events}, nil)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	for k, want := range map[string]string{
+		"Content-Type":      "text/event-stream",
+		"Cache-Control":     "no-cache",
+		"X-Accel-Buffering": "no",
+	} {
+		if got := rec.Header().Get(k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+	if !rec.Flushed {
+		t.Error("events not flushed")
+	}
+	want := "id: 1\nevent: tick\nretry: 1500\ndata: one\ndata: two\n\n" +
+		"data: {\"n\":2}\n\n" +
+		"data: \n\n"
+	if rec.Body.String() != want {
+		t.Errorf("body = %q, want %q", rec.Body, want)
+	}
+}
+
+func TestStreamStopsWithClient(t *testing.T) {
+	e, r := newTestRouter(&handlerChain{})
+	events := make(chan biz.Event)
+	r.GET("/events", func(ctx biz.Context) error {
+		return ctx.Result(&biz.Stream{Events: events}, nil)
+	})
+
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events <- biz.Event{Data: "hello"}
+	buf := make([]byte, len("data: hello\n\n"))
+	if _, err := io.ReadFull(resp.Body, buf); err != nil || string(buf) != "data: hello\n\n" {
+		t.Fatalf("first event %q, %v", buf, err)
+	}
+	resp.Body.Close()
+
+	// The handler returns once the client is gone, without more events.
+	done := make(chan struct{})
+	go func() {
+		srv.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream kept running after the client left")
+	}
+}
@@ -1,13 +1,24 @@
 package boot
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net"
 	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
 
 	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/config"
 	"github.com/youbuwei/doeot-go/pkg/errs"
 )
 
@@ -21,27 +32,91 @@ type rpcRequest struct {
 }
 
 type rpcResponse struct {
-	JSONRPC string          `json:"jsonrpc"`
-	Result  any             `json:"result,omitempty"`
-	Error   *rpcError       `json:"error,omitempty"`
-	ID      json.RawMessage `json:"id"`
+	JSONRPC string
+	Result  any
+	Error   *rpcError
+	ID      json.RawMessage
+}
+
+// MarshalJSON emits exactly one of "result" or "error" (a nil result is
+// still sent as "result": null) and "id": null when the id is unknown.
+func (r rpcResponse) MarshalJSON() ([]byte, error) {
+	id := r.ID
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Error   *rpcError       `json:"error"`
+			ID      json.RawMessage `json:"id"`
+		}{r.JSONRPC, r.Error, id})
+	}
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  any             `json:"result"`
+		ID      json.RawMessage `json:"id"`
+	}{r.JSONRPC, r.Result, id})
 }
 
+// Standard JSON-RPC 2.0 error codes.
+const (
+	rpcCodeParseError     = -32700
+	rpcCodeInvalidRequest = -32600
+	rpcCodeMethodNotFound = -32601
+)
+
 type rpcError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    *rpcErrorData `json:"data,omitempty"`
+}
+
+// rpcErrorData is attached to every error so callers can quote the request ID
+// and clients can restore the business error code and details.
+type rpcErrorData struct {
+	Code      errs.Code     `json:"code,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	Details   *errs.Details `json:"details,omitempty"`
+}
+
+// rpcMethod is a registered JSON-RPC method with its annotation metadata.
+// h is the RPC handler adapted to a HandlerFunc and wrapped in the route's
+// middleware; it reports its outcome through rpcContext.Result.
+type rpcMethod struct {
+	h    biz.HandlerFunc
+	meta *biz.RouteMeta
 }
 
 type rpcServer struct {
-	addr     string
-	handlers map[string]biz.RPCHandlerFunc
+	name             string
+	addr             string
+	batchConcurrency int
+	maxBatch         int
+	maxBodyBytes     int64
+	clientIP         echo.IPExtractor
+	auth             authRegistry
+	chain            *handlerChain
+	errs             routeErrors
+	handlers         map[string]rpcMethod
+
+	discoverOnce sync.Once
+	discoverDoc  map[string]any
 }
 
-func newRPCServer(addr string) *rpcServer {
-	return &rpcServer{
-		addr:     addr,
-		handlers: make(map[string]biz.RPCHandlerFunc),
+func newRPCServer(name string, cfg config.RPCConfig, auth authRegistry, chain *handlerChain) *rpcServer {
+	s := &rpcServer{
+		name:             name,
+		addr:             cfg.Addr,
+		batchConcurrency: cfg.BatchConcurrency,
+		maxBatch:         cfg.MaxBatch,
+		maxBodyBytes:     cfg.MaxBodyBytes,
+		clientIP:         clientIPExtractor(cfg.TrustedProxies),
+		auth:             auth,
+		chain:            chain,
+		handlers:         make(map[string]rpcMethod),
 	}
+	return s
 }
 
 // rpcRouter adapts rpcServer to biz.RPCRouter.
@@ -50,107 +125,303 @@ type rpcRouter struct {
 }
 
 func (r *rpcRouter) Handle(method string, h biz.RPCHandlerFunc, opts ...biz.RouteOption) {
-	// meta could be used for logging/metrics/ACL; ignored in demo.
-	_ = opts
-	r.srv.handlers[method] = h
+	meta := buildRouteMeta(opts)
+	route := "rpc " + method
+	r.srv.auth.check(route, meta)
+
+	wrapped, err := r.srv.chain.build(route, adaptRPC(h), meta)
+	if err != nil {
+		r.srv.errs.add(route, err)
+		return
+	}
+	r.srv.handlers[method] = rpcMethod{h: wrapped, meta: meta}
+}
+
+// adaptRPC turns an RPC handler into a HandlerFunc so HTTP and RPC share
+// middleware. The params travel on the rpcContext; the outcome is reported
+// through ctx.Result like an HTTP handler would.
+func adaptRPC(h biz.RPCHandlerFunc) biz.HandlerFunc {
+	return func(ctx biz.Context) error {
+		rc, ok := rpcContextOf(ctx)
+		if !ok {
+			return errors.New("rpc handler called outside an RPC request")
+		}
+		return ctx.Result(h(ctx, rc.params))
+	}
 }
 
-func (a *App) runRPC() error {
-	srv := newRPCServer(a.cfg.RPC.Addr)
+// buildRPC creates the JSON-RPC server and registers every module's methods.
+func (a *App) buildRPC() (*rpcServer, error) {
+	srv := newRPCServer(a.name, a.cfg.RPC, a.authenticators, a.handlerChain())
 	router := &rpcRouter{srv: srv}
 
 	for _, m := range a.modules {
 		m.RegisterRPC(router)
 	}
+	if err := srv.errs.err(); err != nil {
+		return nil, err
+	}
+	if a.cfg.Docs.Enabled {
+		srv.registerDiscover()
+	}
+	if a.cfg.Debug.Enabled {
+		srv.registerBreakers(a.breakers)
+	}
+	return srv, nil
+}
+
+// runRPC serves srv until ctx is cancelled.
+func (a *App) runRPC(ctx context.Context, srv *rpcServer) error {
+	ln, err := net.Listen("tcp", srv.addr)
+	if err != nil {
+		return err
+	}
+	httpSrv := &http.Server{
+		Addr:    srv.addr,
+		Handler: srv.handler(),
+	}
 
-	return srv.start()
+	return a.serveUntil(ctx,
+		func() error { return httpSrv.Serve(ln) },
+		httpSrv.Shutdown,
+	)
 }
 
-func (s *rpcServer) start() error {
+func (s *rpcServer) handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.handle)
+	return mux
+}
 
-	httpSrv := &http.Server{
-		Addr:    s.addr,
-		Handler: mux,
-	}
+func (s *rpcServer) handle(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
 
-	ln, err := net.Listen("tcp", s.addr)
+	// Accept the caller's request ID (X-Request-ID) or start a new trace.
+	reqID := incomingRequestID(r)
+	w.Header().Set(biz.HeaderRequestID, reqID)
+	r = r.WithContext(requestContext(r, reqID))
+
+	if s.maxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+	}
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return err
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeRPC(w, newRPCBizError(nil, errs.PayloadTooLarge(
+				fmt.Sprintf("request body exceeds the %d bytes limit", tooLarge.Limit)), reqID))
+			return
+		}
+		writeRPC(w, newRPCError(nil, rpcCodeParseError, "parse error", reqID))
+		return
 	}
+	body = bytes.TrimSpace(body)
 
-	return httpSrv.Serve(ln)
+	if len(body) > 0 && body[0] == '[' {
+		s.handleBatch(w, r, body, reqID)
+		return
+	}
+	if !json.Valid(body) {
+		writeRPC(w, newRPCError(nil, rpcCodeParseError, "parse error", reqID))
+		return
+	}
+
+	resp := s.process(r, body, reqID, -1)
+	if resp == nil {
+		// A notification: the server must not reply.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeRPC(w, resp)
 }
 
-func (s *rpcServer) handle(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
+// handleBatch runs every entry of a batch with bounded parallelism and replies
+// with the responses in request order. Notifications contribute nothing; a
+// batch made only of notifications gets no response body at all.
+func (s *rpcServer) handleBatch(w http.ResponseWriter, r *http.Request, body []byte, reqID string) {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		writeRPC(w, newRPCError(nil, rpcCodeParseError, "parse error", reqID))
+		return
+	}
+	if len(batch) == 0 {
+		writeRPC(w, newRPCError(nil, rpcCodeInvalidRequest, "invalid request", reqID))
+		return
+	}
+	if s.maxBatch > 0 && len(batch) > s.maxBatch {
+		writeRPC(w, newRPCError(nil, rpcCodeInvalidRequest,
+			fmt.Sprintf("invalid request: batch of %d exceeds the limit of %d", len(batch), s.maxBatch), reqID))
+		return
+	}
 
-	var req rpcRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeRPCError(w, req.ID, -32700, "parse error")
+	limit := s.batchConcurrency
+	if limit <= 0 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+	results := make([]*rpcResponse, len(batch))
+
+	var wg sync.WaitGroup
+	for i, raw := range batch {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, raw json.RawMessage) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = s.process(r, raw, reqID, i)
+		}(i, raw)
+	}
+	wg.Wait()
+
+	resps := make([]*rpcResponse, 0, len(results))
+	for _, resp := range results {
+		if resp != nil {
+			resps = append(resps, resp)
+		}
+	}
+	if len(resps) == 0 {
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
+	writeRPC(w, resps)
+}
+
+// process handles a single request object and returns its response, or nil
+// when the request is a notification (no "id" member). index is the
+// position of the request in its batch, -1 outside batches.
+func (s *rpcServer) process(r *http.Request, raw json.RawMessage, reqID string, index int) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return newRPCError(nil, rpcCodeInvalidRequest, "invalid request", reqID)
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" || !validRPCID(req.ID) {
+		id := req.ID
+		if !validRPCID(id) {
+			id = nil
+		}
+		return newRPCError(id, rpcCodeInvalidRequest, "invalid request", reqID)
+	}
+	notification := len(req.ID) == 0
 
-	h, ok := s.handlers[req.Method]
+	m, ok := s.handlers[req.Method]
 	if !ok {
-		writeRPCError(w, req.ID, -32601, "method not found")
-		return
+		if notification {
+			return nil
+		}
+		return newRPCError(req.ID, rpcCodeMethodNotFound, "method not found", reqID)
 	}
 
 	// Build a minimal biz.Context for RPC.
-	ctx := &rpcContext{ctx: r.Context()}
+	ctx := &rpcContext{reqInfo: newReqInfo(r, m.meta, s.clientIP), ctx: r.Context(), params: req.Params}
+	if index >= 0 {
+		// Batch entries share the HTTP headers; tell them apart by id, or by
+		// position for notifications.
+		ctx.batchEntry = string(req.ID)
+		if notification {
+			ctx.batchEntry = "#" + strconv.Itoa(index)
+		}
+	}
 
-	result, err := h(ctx, req.Params)
+	start := time.Now()
+	result, err := s.call(ctx, m)
+	// err includes the cause of errs errors, which is not sent to the caller.
+	log.Printf("rpc: method=%s request_id=%s latency=%s err=%v", req.Method, reqID, time.Since(start), err)
+	if notification {
+		return nil
+	}
 	if err != nil {
 		var e *errs.Error
 		if errors.As(err, &e) {
-			writeRPCError(w, req.ID, mapErrCode(e.Code), e.Msg)
-			return
+			return newRPCBizError(req.ID, e, reqID)
 		}
-		writeRPCError(w, req.ID, -32000, "internal error")
-		return
+		return newRPCBizError(req.ID, errs.Internal("internal error"), reqID)
 	}
 
-	resp := rpcResponse{
+	return &rpcResponse{
 		JSONRPC: "2.0",
 		Result:  result,
 		ID:      req.ID,
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(resp)
 }
 
-func writeRPCError(w http.ResponseWriter, id json.RawMessage, code int, msg string) {
-	resp := rpcResponse{
+// call authenticates the caller and invokes the method handler. Panics are
+// turned into internal errors because batch entries run on their own
+// goroutines, outside net/http's recovery.
+func (s *rpcServer) call(ctx *rpcContext, m rpcMethod) (result any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("rpc: panic: %v\n%s", p, debug.Stack())
+			result, err = nil, errs.Internal("internal error")
+		}
+	}()
+
+	if err := s.auth.authenticate(ctx, m.meta); err != nil {
+		return nil, err
+	}
+	if err := m.h(ctx); err != nil && !ctx.hasResult {
+		// A middleware failed without reporting through Result.
+		return nil, err
+	}
+	return ctx.result, ctx.err
+}
+
+// validRPCID reports whether id is absent or a string, number or null.
+func validRPCID(id json.RawMessage) bool {
+	if len(id) == 0 {
+		return true
+	}
+	switch id[0] {
+	case '{', '[', 't', 'f':
+		return false
+	}
+	return true
+}
+
+func newRPCError(id json.RawMessage, code int, msg, reqID string) *rpcResponse {
+	return &rpcResponse{
 		JSONRPC: "2.0",
 		Error: &rpcError{
 			Code:    code,
 			Message: msg,
+			Data:    &rpcErrorData{RequestID: reqID},
 		},
 		ID: id,
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(resp)
 }
 
-func mapErrCode(c errs.Code) int {
-	switch c {
-	case errs.CodeBadRequest:
-		return -32602
-	case errs.CodeNotFound:
-		return -32004
-	case errs.CodeInternal:
-		fallthrough
-	default:
-		return -32000
-	}
+// newRPCBizError reports a business error, keeping its errs.Code and details
+// in error.data.
+func newRPCBizError(id json.RawMessage, e *errs.Error, reqID string) *rpcResponse {
+	resp := newRPCError(id, errs.RPCCode(e.Code), e.Msg, reqID)
+	resp.Error.Data.Code = e.Code
+	resp.Error.Data.Details = e.Details
+	return resp
+}
+
+func writeRPC(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
 }
 
 // rpcContext is a minimal implementation of biz.Context for RPC calls.
-// Bind/JSON/Result are not used in this demo (wrappers work directly with params/result).
+// Bind/JSON are not supported (wrappers decode params themselves); Result
+// records the outcome that becomes the JSON-RPC response.
 type rpcContext struct {
-	ctx context.Context
+	reqInfo
+	ctx    context.Context
+	params json.RawMessage
+	// batchEntry identifies the request within its batch, "" outside one.
+	batchEntry string
+
+	hasResult bool
+	result    any
+	err       error
+}
+
+// rpcContextOf finds the rpcContext under ctx, which middleware may have
+// wrapped.
+func rpcContextOf(ctx biz.Context) (*rpcContext, bool) {
+	return contextAs[*rpcContext](ctx)
 }
 
 func (c *rpcContext) RequestContext() context.Context {
@@ -158,7 +429,7 @@ func (c *rpcContext) RequestContext() context.Context {
 }
 
 func (c *rpcContext) RequestID() string {
-	return ""
+	return biz.RequestIDFrom(c.ctx)
 }
 
 func (c *rpcContext) Bind(out any) error {
@@ -171,8 +442,9 @@ func (c *rpcContext) JSON(status int, body any) error {
 	return errors.New("JSON not supported for RPC context")
 }
 
+// Result records the response; the last call wins.
 func (c *rpcContext) Result(data any, err error) error {
-	_ = data
-	_ = err
-	return errors.New("Result not supported for RPC context")
+	c.hasResult = true
+	c.result, c.err = data, err
+	return nil
 }
@@ -0,0 +1,78 @@
+package boot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/config"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+)
+
+// rpcReply is a decoded single JSON-RPC response.
+type rpcReply struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// callRPC sends one request for method to s and decodes the response.
+func callRPC(t *testing.T, s *rpcServer, method, params string) rpcReply {
+	t.Helper()
+	if params == "" {
+		params = "null"
+	}
+	body := `{"jsonrpc":"2.0","method":"` + method + `","params":` + params + `,"id":1}`
+	rec := httptest.NewRecorder()
+	s.handle(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	var reply rpcReply
+	if err := json.Unmarshal(rec.Body.Bytes(), &reply); err != nil {
+		t.Fatalf("%s: response %q: %v", method, rec.Body, err)
+	}
+	return reply
+}
+
+func TestRPCRequestLimits(t *testing.T) {
+	s := newRPCServer("test", config.RPCConfig{BatchConcurrency: 2, MaxBatch: 2, MaxBodyBytes: 256}, authRegistry{}, &handlerChain{})
+	(&rpcRouter{srv: s}).Handle("Ping.Ping", func(biz.Context, json.RawMessage) (any, error) {
+		return "pong", nil
+	})
+
+	call := `{"jsonrpc":"2.0","method":"Ping.Ping","id":1}`
+	cases := []struct {
+		name     string
+		body     string
+		wantCode int // 0: success
+	}{
+		{"batch within limit", "[" + call + "," + call + "]", 0},
+		{"batch over limit", "[" + call + "," + call + "," + call + "]", rpcCodeInvalidRequest},
+		{"body over limit", `{"jsonrpc":"2.0","method":"Ping.Ping","params":"` + strings.Repeat("x", 300) + `","id":1}`,
+			errs.RPCCode(errs.CodePayloadTooLarge)},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		s.handle(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)))
+
+		body := rec.Body.String()
+		var single struct {
+			Error *rpcError `json:"error"`
+		}
+		if strings.HasPrefix(body, "[") {
+			var batch []json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &batch); err != nil || len(batch) != 2 {
+				t.Errorf("%s: response %s", tc.name, body)
+			}
+		} else if err := json.Unmarshal(rec.Body.Bytes(), &single); err != nil {
+			t.Errorf("%s: response %s", tc.name, body)
+		}
+
+		switch {
+		case tc.wantCode == 0 && single.Error != nil:
+			t.Errorf("%s: unexpected error %+v", tc.name, single.Error)
+		case tc.wantCode != 0 && (single.Error == nil || single.Error.Code != tc.wantCode):
+			t.Errorf("%s: response %s, want error code %d", tc.name, body, tc.wantCode)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Package breaker implements the circuit breakers behind @CircuitBreaker.
+//
+// A Breaker counts outcomes in a sliding window. Once at least MinRequests
+// calls were seen and the share of failures reaches FailureRatio it opens
+// and rejects calls for Cooldown; then it lets a single probe through
+// (half-open) and closes again if the probe succeeds.
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults for zero Config fields.
+const (
+	DefaultMinRequests = 10
+	DefaultCooldown    = 10 * time.Second
+)
+
+// buckets is the resolution of the sliding window.
+const buckets = 10
+
+// State is the state of a Breaker.
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// MarshalText encodes the state by name in JSON.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Config tunes a Breaker.
+type Config struct {
+	// FailureRatio in (0, 1] trips the breaker.
+	FailureRatio float64
+	// Window is the sliding window failures are counted in.
+	Window time.Duration
+	// MinRequests in the window before the ratio is considered.
+	MinRequests int
+	// Cooldown is how long the breaker stays open before probing.
+	Cooldown time.Duration
+}
+
+// Valid reports whether the config can be enforced.
+func (c Config) Valid() bool {
+	return c.FailureRatio > 0 && c.FailureRatio <= 1 && c.Window > 0 && c.MinRequests >= 0 && c.Cooldown >= 0
+}
+
+func (c Config) withDefaults() Config {
+	if c.MinRequests == 0 {
+		c.MinRequests = DefaultMinRequests
+	}
+	if c.Cooldown == 0 {
+		c.Cooldown = DefaultCooldown
+	}
+	return c
+}
+
+type counts struct {
+	requests, failures int
+}
+
+// Breaker is a circuit breaker. It is safe for concurrent use.
+type Breaker struct {
+	name string
+	cfg  Config
+	now  func() time.Time
+
+	mu         sync.Mutex
+	state      State
+	generation uint64 // bumped on every state change
+	window     [buckets]counts
+	cur        int
+	curStart   time.Time
+	openedAt   time.Time
+	probing    bool
+}
+
+// New returns a closed breaker.
+func New(name string, cfg Config) *Breaker {
+	return NewWithClock(name, cfg, time.Now)
+}
+
+// NewWithClock is New with the current time read from now, e.g. a fake
+// clock in tests.
+func NewWithClock(name string, cfg Config, now func() time.Time) *Breaker {
+	b := &Breaker{name: name, cfg: cfg.withDefaults(), now: now}
+	b.curStart = b.now()
+	return b
+}
+
+// Name returns the name the breaker was created with.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// Allow reports whether a call may proceed. Callers that were allowed must
+// report the outcome with Done, passing the returned generation.
+func (b *Breaker) Allow() (generation uint64, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case Open:
+		if now.Sub(b.openedAt) < b.cfg.Cooldown {
+			return 0, false
+		}
+		b.setState(HalfOpen, now)
+		fallthrough
+	case HalfOpen:
+		if b.probing {
+			return 0, false
+		}
+		b.probing = true
+	}
+	return b.generation, true
+}
+
+// Done records the outcome of a call admitted by Allow. Outcomes of calls
+// admitted before the last state change are ignored.
+func (b *Breaker) Done(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+	now := b.now()
+	switch b.state {
+	case HalfOpen:
+		if success {
+			b.setState(Closed, now)
+		} else {
+			b.setState(Open, now)
+		}
+	case Closed:
+		b.advance(now)
+		c := &b.window[b.cur]
+		c.requests++
+		if !success {
+			c.failures++
+		}
+		total := b.totals()
+		if total.requests >= b.cfg.MinRequests &&
+			float64(total.failures) >= b.cfg.FailureRatio*float64(total.requests) {
+			b.setState(Open, now)
+		}
+	}
+}
+
+// Snapshot describes a breaker for introspection.
+type Snapshot struct {
+	Name         string    `json:"name"`
+	State        State     `json:"state"`
+	Requests     int       `json:"requests"`
+	Failures     int       `json:"failures"`
+	FailureRatio float64   `json:"failure_ratio"`
+	Window       string    `json:"window"`
+	OpenedAt     time.Time `json:"opened_at,omitzero"`
+	RetryAt      time.Time `json:"retry_at,omitzero"`
+}
+
+// Snapshot returns the current state and window counts.
+func (b *Breaker) Snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.advance(now)
+	total := b.totals()
+	s := Snapshot{
+		Name:         b.name,
+		State:        b.state,
+		Requests:     total.requests,
+		Failures:     total.failures,
+		FailureRatio: b.cfg.FailureRatio,
+		Window:       b.cfg.Window.String(),
+	}
+	if b.state == Open && now.Sub(b.openedAt) >= b.cfg.Cooldown {
+		// Not probed yet, but the next call will be.
+		s.State = HalfOpen
+	}
+	if b.state != Closed {
+		s.OpenedAt = b.openedAt
+		s.RetryAt = b.openedAt.Add(b.cfg.Cooldown)
+	}
+	return s
+}
+
+func (b *Breaker) setState(s State, now time.Time) {
+	b.state = s
+	b.generation++
+	b.probing = false
+	switch s {
+	case Open:
+		b.openedAt = now
+	case Closed:
+		b.window = [buckets]counts{}
+		b.cur, b.curStart = 0, now
+	}
+}
+
+// advance rotates the window so the current bucket covers now.
+func (b *Breaker) advance(now time.Time) {
+	width := b.cfg.Window / buckets
+	if width <= 0 {
+		width = 1
+	}
+	steps := int(now.Sub(b.curStart) / width)
+	if steps <= 0 {
+		return
+	}
+	if steps > buckets {
+		steps = buckets
+	}
+	for i := 0; i < steps; i++ {
+		b.cur = (b.cur + 1) % buckets
+		b.window[b.cur] = counts{}
+	}
+	b.curStart = b.curStart.Add(time.Duration(int(now.Sub(b.curStart)/width)) * width)
+}
+
+func (b *Breaker) totals() counts {
+	var t counts
+	for _, c := range b.window {
+		t.requests += c.requests
+		t.failures += c.failures
+	}
+	return t
+}
@@ -89,6 +89,7 @@ func (a *App) printUsage() {
   doeot dev -services http-api,json-rpc -dev-http :18080
   doeot modgen -name order
   doeot bizgen -module user
+  doeot openapi -o api/openapi.json
 
 提示:
   每个子命令通常也支持 -h/--help 查看自己的参数。`)
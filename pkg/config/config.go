@@ -1,102 +1,104 @@
 package config
 
 import (
-	"log"
-	"os"
-	"strconv"
-
-	"github.com/joho/godotenv"
+	"errors"
+	"fmt"
+	"net"
+	"time"
 )
 
 // MySQLConfig holds database settings.
 type MySQLConfig struct {
-	DSN        string
-	MaxIdle    int
-	MaxOpen    int
-	MaxLifeMin int
+	DSN        string `config:"dsn"`
+	MaxIdle    int    `config:"max_idle"`
+	MaxOpen    int    `config:"max_open"`
+	MaxLifeMin int    `config:"max_life_min"`
 }
 
 // HTTPConfig holds HTTP server settings.
 type HTTPConfig struct {
-	Addr string
+	Addr string `config:"addr"`
+	// TrustedProxies lists the IPs or CIDRs of the reverse proxies whose
+	// X-Forwarded-For / X-Real-IP headers name the client. Requests from
+	// anywhere else are identified by their socket address.
+	TrustedProxies []string `config:"trusted_proxies"`
 }
 
 // RPCConfig holds RPC server settings.
 type RPCConfig struct {
-	Addr string
+	Addr string `config:"addr"`
+	// BatchConcurrency bounds how many entries of one JSON-RPC batch run in parallel.
+	BatchConcurrency int `config:"batch_concurrency"`
+	// MaxBatch bounds the number of entries in one JSON-RPC batch.
+	MaxBatch int `config:"max_batch"`
+	// MaxBodyBytes bounds the size of a request body.
+	MaxBodyBytes int64 `config:"max_body_bytes"`
+	// TrustedProxies is HTTPConfig.TrustedProxies for the RPC port.
+	TrustedProxies []string `config:"trusted_proxies"`
 }
 
-// AppConfig groups all configuration parts.
-type AppConfig struct {
-	MySQL MySQLConfig
-	HTTP  HTTPConfig
-	RPC   RPCConfig
+// ShutdownConfig controls graceful shutdown.
+type ShutdownConfig struct {
+	// Timeout bounds how long in-flight requests and OnStop hooks may take
+	// once a stop signal is received.
+	Timeout time.Duration `config:"timeout"`
 }
 
-// Load returns config for a given service name.
-// It loads .env (if present), then reads from environment variables with sane defaults.
-// Service-specific overrides can be done via env if needed.
-func Load(serviceName string) AppConfig {
-	// Best-effort load .env; ignore error if file is missing.
-	_ = godotenv.Load()
+// DocsConfig controls the API docs served by the HTTP server.
+type DocsConfig struct {
+	// Enabled mounts /openapi.json and /docs, and the rpc.discover method on
+	// the RPC port. Keep it off in production.
+	Enabled bool `config:"enabled"`
+}
 
-	mysqlDSN := getenv("MYSQL_DSN",
-		"root:root@tcp(127.0.0.1:3306)/mall?parseTime=true&loc=Local",
-	)
-	mysqlMaxIdle := getenvInt("MYSQL_MAX_IDLE", 10)
-	mysqlMaxOpen := getenvInt("MYSQL_MAX_OPEN", 50)
-	mysqlMaxLifeMin := getenvInt("MYSQL_MAX_LIFE_MIN", 60)
+// DebugConfig controls introspection endpoints.
+type DebugConfig struct {
+	// Enabled mounts GET /debug/breakers (HTTP) and rpc.breakers (JSON-RPC).
+	Enabled bool `config:"enabled"`
+}
+
+// AppConfig groups all configuration parts.
+type AppConfig struct {
+	// Env is the environment whose config file was loaded (APP_ENV / -env),
+	// e.g. "prod"; empty if none.
+	Env string `config:"-"`
 
-	httpAddr := os.Getenv("HTTP_ADDR")
-	rpcAddr := os.Getenv("RPC_ADDR")
+	MySQL    MySQLConfig    `config:"mysql"`
+	HTTP     HTTPConfig     `config:"http"`
+	RPC      RPCConfig      `config:"rpc"`
+	Shutdown ShutdownConfig `config:"shutdown"`
+	Docs     DocsConfig     `config:"docs"`
+	Debug    DebugConfig    `config:"debug"`
+}
 
-	// Some sane defaults based on serviceName if env not set explicitly.
-	switch serviceName {
-	case "http-api":
-		if httpAddr == "" {
-			httpAddr = ":8080"
+// Validate reports values the services cannot start with.
+func (c *AppConfig) Validate() error {
+	var errs []error
+	check := func(ok bool, key, msg string) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, msg))
 		}
-		// http-api is HTTP-only by default
-		rpcAddr = ""
-	case "json-rpc":
-		if rpcAddr == "" {
-			rpcAddr = ":19001"
-		}
-		// json-rpc is RPC-only by default
-		httpAddr = ""
 	}
-
-	return AppConfig{
-		MySQL: MySQLConfig{
-			DSN:        mysqlDSN,
-			MaxIdle:    mysqlMaxIdle,
-			MaxOpen:    mysqlMaxOpen,
-			MaxLifeMin: mysqlMaxLifeMin,
-		},
-		HTTP: HTTPConfig{
-			Addr: httpAddr,
-		},
-		RPC: RPCConfig{
-			Addr: rpcAddr,
-		},
+	check(c.MySQL.DSN != "", "mysql.dsn", "must not be empty")
+	check(c.MySQL.MaxIdle >= 0, "mysql.max_idle", "must not be negative")
+	check(c.MySQL.MaxOpen >= 0, "mysql.max_open", "must not be negative")
+	check(c.MySQL.MaxLifeMin >= 0, "mysql.max_life_min", "must not be negative")
+	check(c.RPC.BatchConcurrency > 0, "rpc.batch_concurrency", "must be positive")
+	check(c.RPC.MaxBatch > 0, "rpc.max_batch", "must be positive")
+	check(c.RPC.MaxBodyBytes > 0, "rpc.max_body_bytes", "must be positive")
+	check(c.Shutdown.Timeout > 0, "shutdown.timeout", "must be positive")
+	for _, p := range c.HTTP.TrustedProxies {
+		check(validProxy(p), "http.trusted_proxies", fmt.Sprintf("%q is not an IP or CIDR", p))
 	}
-}
-
-func getenv(key, def string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+	for _, p := range c.RPC.TrustedProxies {
+		check(validProxy(p), "rpc.trusted_proxies", fmt.Sprintf("%q is not an IP or CIDR", p))
 	}
-	return def
+	return errors.Join(errs...)
 }
 
-func getenvInt(key string, def int) int {
-	if v := os.Getenv(key); v != "" {
-		n, err := strconv.Atoi(v)
-		if err != nil {
-			log.Printf("invalid int env %s=%s, use default %d", key, v, def)
-			return def
-		}
-		return n
+func validProxy(s string) bool {
+	if _, _, err := net.ParseCIDR(s); err == nil {
+		return true
 	}
-	return def
+	return net.ParseIP(s) != nil
 }
@@ -0,0 +1,337 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// fieldKey returns the key of a struct field: its `config` tag, else its
+// snake_cased name. Unexported and `config:"-"` fields have none.
+func fieldKey(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag := f.Tag.Get("config")
+	switch tag {
+	case "-":
+		return "", false
+	case "":
+		return snakeCase(f.Name), true
+	}
+	return tag, true
+}
+
+// snakeCase turns MaxIdle into max_idle and HTTPAddr into http_addr.
+func snakeCase(s string) string {
+	r := []rune(s)
+	var b strings.Builder
+	for i, c := range r {
+		if unicode.IsUpper(c) {
+			prevLower := i > 0 && !unicode.IsUpper(r[i-1])
+			nextLower := i > 0 && i+1 < len(r) && unicode.IsLower(r[i+1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// isLeaf reports whether values of t are set as a whole rather than key by
+// key.
+func isLeaf(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	return t.Kind() != reflect.Struct
+}
+
+// leaf is a key that can be set from the environment and flags.
+type leaf struct {
+	path string
+	env  string
+	typ  reflect.Type
+}
+
+// leaves lists the settable keys below prefix. Maps are left to the config
+// files.
+func leaves(prefix string, t reflect.Type, out []leaf) []leaf {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, ok := fieldKey(f)
+		if !ok {
+			continue
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		switch {
+		case !isLeaf(f.Type):
+			out = leaves(path, f.Type, out)
+		case f.Type.Kind() != reflect.Map:
+			out = append(out, leaf{path: path, env: envName(path), typ: f.Type})
+		}
+	}
+	return out
+}
+
+// envName is the environment variable of a key: mysql.max_idle is
+// MYSQL_MAX_IDLE.
+func envName(path string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(path))
+}
+
+// decoder decodes the merged tree into structs, collecting every error.
+type decoder struct {
+	sources map[string]string
+	errs    []error
+}
+
+func (d *decoder) fail(path, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if src := d.sources[path]; src != "" {
+		msg += " (from " + src + ")"
+	}
+	d.errs = append(d.errs, fmt.Errorf("%s: %s", path, msg))
+}
+
+// structFields decodes the keys of m into the fields of rv, reporting keys
+// without a field.
+func (d *decoder) structFields(prefix string, m map[string]any, rv reflect.Value) {
+	fields := make(map[string]int)
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if key, ok := fieldKey(t.Field(i)); ok {
+			fields[key] = i
+		}
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		path := join(prefix, k)
+		i, ok := fields[k]
+		if !ok {
+			d.fail(path, "unknown key")
+			continue
+		}
+		d.value(path, m[k], rv.Field(i))
+	}
+}
+
+// value decodes raw, as found in YAML, TOML, the environment or flags, into
+// rv.
+func (d *decoder) value(path string, raw any, rv reflect.Value) {
+	if rv.Kind() == reflect.Pointer {
+		// Like maps, decode into a copy rather than through a shared pointer.
+		p := reflect.New(rv.Type().Elem())
+		if !rv.IsNil() {
+			p.Elem().Set(rv.Elem())
+		}
+		d.value(path, raw, p.Elem())
+		rv.Set(p)
+		return
+	}
+
+	switch {
+	case rv.Type() == durationType:
+		s, ok := raw.(string)
+		if !ok {
+			d.fail(path, "want a duration such as \"15s\", got %v", raw)
+			return
+		}
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			d.fail(path, "invalid duration %q", s)
+			return
+		}
+		rv.SetInt(int64(v))
+		return
+	case raw != nil && reflect.TypeOf(raw) == rv.Type() && rv.Kind() != reflect.Map && rv.Kind() != reflect.Slice:
+		rv.Set(reflect.ValueOf(raw))
+		return
+	case rv.Addr().Type().Implements(textUnmarshalerType):
+		s, ok := raw.(string)
+		if !ok {
+			d.fail(path, "want a string, got %v", raw)
+			return
+		}
+		if err := rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			d.fail(path, "invalid value %q: %v", s, err)
+		}
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			d.fail(path, "want a string, got %v", raw)
+			return
+		}
+		rv.SetString(s)
+	case reflect.Bool:
+		switch v := raw.(type) {
+		case bool:
+			rv.SetBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				d.fail(path, "invalid bool %q", v)
+				return
+			}
+			rv.SetBool(b)
+		default:
+			d.fail(path, "want a bool, got %v", raw)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := toInt(raw)
+		if !ok || rv.OverflowInt(n) {
+			d.fail(path, "want an integer, got %v", raw)
+			return
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := toInt(raw)
+		if !ok || n < 0 || rv.OverflowUint(uint64(n)) {
+			d.fail(path, "want a non-negative integer, got %v", raw)
+			return
+		}
+		rv.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat(raw)
+		if !ok || rv.OverflowFloat(f) {
+			d.fail(path, "want a number, got %v", raw)
+			return
+		}
+		rv.SetFloat(f)
+	case reflect.Slice:
+		var items []any
+		switch v := raw.(type) {
+		case []any:
+			items = v
+		case string:
+			// Lists from the environment and flags are comma separated.
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					items = append(items, s)
+				}
+			}
+		default:
+			d.fail(path, "want a list, got %v", raw)
+			return
+		}
+		out := reflect.MakeSlice(rv.Type(), len(items), len(items))
+		for i, item := range items {
+			d.value(fmt.Sprintf("%s[%d]", path, i), item, out.Index(i))
+		}
+		rv.Set(out)
+	case reflect.Map:
+		m, ok := raw.(map[string]any)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			d.fail(path, "want a table, got %v", raw)
+			return
+		}
+		// Decode into a copy: the map may be shared with the defaults.
+		out := reflect.MakeMapWithSize(rv.Type(), rv.Len()+len(m))
+		iter := rv.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), iter.Value())
+		}
+		for k, v := range m {
+			key := reflect.ValueOf(k).Convert(rv.Type().Key())
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if old := out.MapIndex(key); old.IsValid() {
+				elem.Set(old)
+			}
+			d.value(join(path, k), v, elem)
+			out.SetMapIndex(key, elem)
+		}
+		rv.Set(out)
+	case reflect.Struct:
+		m, ok := raw.(map[string]any)
+		if !ok {
+			d.fail(path, "want a table, got %v", raw)
+			return
+		}
+		d.structFields(path, m, rv)
+	case reflect.Interface:
+		if raw != nil {
+			rv.Set(reflect.ValueOf(raw))
+		}
+	default:
+		d.fail(path, "unsupported type %s", rv.Type())
+	}
+}
+
+func toInt(raw any) (int64, bool) {
+	switch v := raw.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func toFloat(raw any) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed defaults/*.yaml
+var defaults embed.FS
+
+// Environment variables locating the config files; -config and -env
+// override them.
+const (
+	EnvConfigDir = "CONFIG_DIR"
+	EnvAppEnv    = "APP_ENV"
+)
+
+// DefaultDir is where Load looks for config files.
+const DefaultDir = "config"
+
+// fileExts are the supported config file formats, tried in order.
+var fileExts = []string{".yaml", ".yml", ".toml"}
+
+// Load builds the configuration of serviceName from these layers, each
+// overriding the ones before:
+//
+//  1. defaults embedded in this package (defaults/app.yaml and
+//     defaults/<service>.yaml)
+//  2. <dir>/app.yaml
+//  3. <dir>/<service>.yaml
+//  4. <dir>/<env>.yaml, env being APP_ENV or -env, e.g. "prod"
+//  5. .env
+//  6. environment variables: MYSQL_DSN for mysql.dsn, PAY_TIMEOUT for the
+//     timeout key of the "pay" section
+//  7. flags in args: -mysql.dsn=..., -pay.timeout=...
+//
+// dir is "config" unless CONFIG_DIR or -config says otherwise. Files are
+// optional and may also be .yml or .toml. The result is decoded into
+// AppConfig, the registered sections (RegisterSection) and Values; unknown
+// keys, invalid values and failed Validate methods are all reported in the
+// error. The layers are kept for Watch to put provider values on top of.
+// With -h in args the usage is printed and flag.ErrHelp returned.
+func Load(serviceName string, args ...string) (AppConfig, error) {
+	// .env only fills variables missing from the environment, which gives
+	// it its place below real environment variables.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return AppConfig{}, fmt.Errorf("config: .env: %w", err)
+	}
+
+	secs := registeredSections()
+	keys := leaves("", reflect.TypeOf(AppConfig{}), nil)
+	for _, s := range secs {
+		keys = leaves(s.name, s.typ(), keys)
+	}
+
+	opts, err := parseFlags(serviceName, args, keys)
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	t := newTree()
+	for _, name := range []string{"app", serviceName} {
+		if err := t.mergeEmbedded(name); err != nil {
+			return AppConfig{}, err
+		}
+	}
+	files := []string{"app", serviceName}
+	if opts.env != "" {
+		files = append(files, opts.env)
+	}
+	for _, name := range files {
+		if err := t.mergeFile(opts.dir, name); err != nil {
+			return AppConfig{}, err
+		}
+	}
+	for _, k := range keys {
+		if v := os.Getenv(k.env); v != "" {
+			t.set(k.path, v, "$"+k.env)
+		}
+	}
+	for _, o := range opts.overrides {
+		t.set(o.path, o.value, "-"+o.path)
+	}
+
+	cfg, err := t.decode(secs)
+	if err != nil {
+		return AppConfig{}, err
+	}
+	cfg.Env = opts.env
+	setLoaded(t)
+	return cfg, nil
+}
+
+type override struct {
+	path, value string
+}
+
+type loadOptions struct {
+	dir       string
+	env       string
+	overrides []override
+}
+
+// parseFlags reads -config, -env and one flag per settable key.
+func parseFlags(serviceName string, args []string, keys []leaf) (loadOptions, error) {
+	opts := loadOptions{dir: os.Getenv(EnvConfigDir), env: os.Getenv(EnvAppEnv)}
+	if opts.dir == "" {
+		opts.dir = DefaultDir
+	}
+
+	fset := flag.NewFlagSet(serviceName, flag.ContinueOnError)
+	fset.StringVar(&opts.dir, "config", opts.dir, "config directory ($"+EnvConfigDir+")")
+	fset.StringVar(&opts.env, "env", opts.env, "environment whose <config>/<env>.yaml is loaded ($"+EnvAppEnv+")")
+	for _, k := range keys {
+		path := k.path
+		fset.Func(path, fmt.Sprintf("`%s` value, also set by $%s", k.typ, k.env), func(v string) error {
+			opts.overrides = append(opts.overrides, override{path: path, value: v})
+			return nil
+		})
+	}
+	if err := fset.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return opts, err
+		}
+		return opts, fmt.Errorf("config: %w", err)
+	}
+	if fset.NArg() > 0 {
+		return opts, fmt.Errorf("config: unexpected argument %q", fset.Arg(0))
+	}
+	return opts, nil
+}
+
+// tree is the merged configuration, nested maps as decoded from YAML, plus
+// where each value came from for error messages.
+type tree struct {
+	root    map[string]any
+	sources map[string]string
+}
+
+func newTree() *tree {
+	return &tree{root: make(map[string]any), sources: make(map[string]string)}
+}
+
+func (t *tree) mergeEmbedded(name string) error {
+	file := "defaults/" + name + ".yaml"
+	b, err := defaults.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return t.mergeBytes(b, ".yaml", "embedded "+file)
+}
+
+// mergeFile merges <dir>/<name> in whichever format exists.
+func (t *tree) mergeFile(dir, name string) error {
+	var found string
+	for _, ext := range fileExts {
+		path := filepath.Join(dir, name+ext)
+		b, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		if found != "" {
+			return fmt.Errorf("config: both %s and %s exist", found, path)
+		}
+		found = path
+		if err := t.mergeBytes(b, ext, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tree) mergeBytes(b []byte, ext, src string) error {
+	m, err := parse(b, ext)
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", src, err)
+	}
+	t.merge(t.root, "", m, src)
+	return nil
+}
+
+// parse decodes TOML when ext is ".toml" and YAML, which covers JSON,
+// otherwise.
+func parse(b []byte, ext string) (map[string]any, error) {
+	m := make(map[string]any)
+	var err error
+	if ext == ".toml" {
+		err = toml.Unmarshal(b, &m)
+	} else {
+		err = yaml.Unmarshal(b, &m)
+	}
+	return m, err
+}
+
+// merge copies m into dst; tables are merged key by key, anything else
+// replaces the previous value.
+func (t *tree) merge(dst map[string]any, prefix string, m map[string]any, src string) {
+	for k, v := range m {
+		path := join(prefix, k)
+		if sub, ok := v.(map[string]any); ok {
+			d, ok := dst[k].(map[string]any)
+			if !ok {
+				d = make(map[string]any)
+				dst[k] = d
+			}
+			t.merge(d, path, sub, src)
+			continue
+		}
+		dst[k] = v
+		t.sources[path] = src
+	}
+}
+
+// clone returns a deep copy of t, so that layers can be added on top of it.
+func (t *tree) clone() *tree {
+	c := newTree()
+	c.merge(c.root, "", t.root, "")
+	for k, v := range t.sources {
+		c.sources[k] = v
+	}
+	return c
+}
+
+// set stores a string value from the environment or a flag at path.
+func (t *tree) set(path, value, src string) {
+	parts := strings.Split(path, ".")
+	m := t.root
+	for _, p := range parts[:len(parts)-1] {
+		sub, ok := m[p].(map[string]any)
+		if !ok {
+			sub = make(map[string]any)
+			m[p] = sub
+		}
+		m = sub
+	}
+	m[parts[len(parts)-1]] = value
+	t.sources[path] = src
+}
+
+// decode fills AppConfig, the sections and the Values and validates them.
+// Values only change when everything is valid.
+func (t *tree) decode(secs []section) (AppConfig, error) {
+	var cfg AppConfig
+	d := &decoder{sources: t.sources}
+
+	app := make(map[string]any, len(t.root))
+	for k, v := range t.root {
+		app[k] = v
+	}
+	var commits []func() func()
+	for _, s := range secs {
+		raw, ok := app[s.name]
+		delete(app, s.name)
+		if dv, dyn := s.ptr.(dynamic); dyn {
+			if commit := dv.prepare(d, raw, ok); commit != nil {
+				commits = append(commits, commit)
+			}
+			continue
+		}
+		if ok {
+			d.value(s.name, raw, reflect.ValueOf(s.ptr).Elem())
+		}
+	}
+	d.structFields("", app, reflect.ValueOf(&cfg).Elem())
+
+	if len(d.errs) == 0 {
+		if err := cfg.Validate(); err != nil {
+			d.errs = append(d.errs, err)
+		}
+		for _, s := range secs {
+			if v, ok := s.ptr.(Validator); ok {
+				if err := v.Validate(); err != nil {
+					d.errs = append(d.errs, fmt.Errorf("%s: %w", s.name, err))
+				}
+			}
+		}
+	}
+	if len(d.errs) > 0 {
+		return AppConfig{}, fmt.Errorf("config: %w", errors.Join(d.errs...))
+	}
+	commitAll(commits)
+	return cfg, nil
+}
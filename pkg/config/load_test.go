@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testPay struct {
+	MerchantID string        `config:"merchant_id"`
+	Region     string        `config:"region"`
+	Channel    string        `config:"channel"`
+	Timeout    time.Duration `config:"timeout"`
+}
+
+var testPayCfg = testPay{Region: "default", Timeout: time.Second}
+
+func init() {
+	RegisterSection("test_pay", &testPayCfg)
+}
+
+// writeFiles creates the named files with their contents in dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// unsetenv unsets key for the rest of the test, restoring it afterwards.
+func unsetenv(t *testing.T, key string) {
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func TestLoadLayers(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeFiles(t, dir, map[string]string{
+		"app.yaml":  "mysql:\n  max_open: 20\n  max_life_min: 1\n",
+		"test.toml": "[mysql]\nmax_life_min = 2\n\n[test_pay]\nmerchant_id = \"toml\"\n",
+		"prod.yaml": "test_pay:\n  merchant_id: prod\n  region: file\n  channel: file\n",
+		".env":      "TEST_PAY_REGION=dotenv\nTEST_PAY_CHANNEL=dotenv\n",
+	})
+	// .env only fills variables missing from the environment.
+	unsetenv(t, "TEST_PAY_REGION")
+	t.Setenv("TEST_PAY_CHANNEL", "env")
+	t.Setenv("TEST_PAY_TIMEOUT", "5s")
+
+	cfg, err := Load("test", "-config", dir, "-env", "prod", "-test_pay.timeout=7s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		layer string
+		got   any
+		want  any
+	}{
+		{"embedded defaults", cfg.MySQL.MaxIdle, 10},
+		{"app.yaml", cfg.MySQL.MaxOpen, 20},
+		{"<service>.toml over app.yaml", cfg.MySQL.MaxLifeMin, 2},
+		{"<env>.yaml over <service>.toml", testPayCfg.MerchantID, "prod"},
+		{".env over files", testPayCfg.Region, "dotenv"},
+		{"environment over .env", testPayCfg.Channel, "env"},
+		{"flags over environment", testPayCfg.Timeout, 7 * time.Second},
+		{"-env", cfg.Env, "prod"},
+	}
+	for _, tc := range cases {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.layer, tc.got, tc.want)
+		}
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]string
+		args  []string
+		want  string
+	}{
+		{
+			name:  "unknown section key",
+			files: map[string]string{"app.yaml": "test_pay:\n  merchant: x\n"},
+			want:  "test_pay.merchant: unknown key (from ",
+		},
+		{
+			name:  "unknown AppConfig key",
+			files: map[string]string{"test.toml": "[http]\nport = 80\n"},
+			want:  "http.port: unknown key (from ",
+		},
+		{
+			name:  "unknown top-level key",
+			files: map[string]string{"app.yaml": "payment:\n  timeout: 1s\n"},
+			want:  "payment: unknown key",
+		},
+		{
+			name: "unknown flag",
+			args: []string{"-test_pay.merchant=x"},
+			want: "flag provided but not defined: -test_pay.merchant",
+		},
+		{
+			name:  "invalid value",
+			files: map[string]string{"app.yaml": "test_pay:\n  timeout: soon\n"},
+			want:  "test_pay.timeout: ",
+		},
+		{
+			name:  "two formats",
+			files: map[string]string{"app.yaml": "", "app.toml": ""},
+			want:  "both ",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+			writeFiles(t, dir, tc.files)
+
+			_, err := Load("test", append([]string{"-config", dir}, tc.args...)...)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// Provider is a source of dynamic configuration such as a config center.
+// Its values are layered above everything Load reads and may only set
+// Values (NewValue); AppConfig and sections are fixed at startup.
+type Provider interface {
+	// Name identifies the provider in errors and logs.
+	Name() string
+	// Get returns the current configuration, keyed like a config file.
+	Get(ctx context.Context) (map[string]any, error)
+	// Watch returns a channel that receives whenever the configuration
+	// may have changed, until ctx is done.
+	Watch(ctx context.Context) (<-chan struct{}, error)
+}
+
+var loaded struct {
+	sync.Mutex
+	t *tree
+}
+
+func setLoaded(t *tree) {
+	loaded.Lock()
+	defer loaded.Unlock()
+	loaded.t = t
+}
+
+// Watch applies the configuration of providers to the Values, then reapplies
+// it whenever a provider reports a change until ctx is done. Later providers
+// override earlier ones.
+//
+// A reload is decoded and validated as a whole before any Value changes, so
+// modules never see half of it; a rejected reload or a failed fetch is
+// logged and the current values stay. The first fetch and apply must
+// succeed, or Watch returns the error. Load must have been called before.
+func Watch(ctx context.Context, providers ...Provider) error {
+	loaded.Lock()
+	base := loaded.t
+	loaded.Unlock()
+	if base == nil {
+		return errors.New("config: Watch called before Load")
+	}
+
+	r := &reloader{base: base, providers: providers, layers: make([]map[string]any, len(providers))}
+	for i, p := range providers {
+		m, err := p.Get(ctx)
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", p.Name(), err)
+		}
+		r.layers[i] = m
+	}
+	if err := r.apply(); err != nil {
+		return err
+	}
+	for _, p := range providers {
+		acknowledge(p)
+	}
+
+	for i, p := range providers {
+		changes, err := p.Watch(ctx)
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", p.Name(), err)
+		}
+		go r.loop(ctx, i, changes)
+	}
+	return nil
+}
+
+// acknowledger is implemented by providers that report changes relative to
+// the configuration last applied, such as HTTPProvider. applied is called
+// once the result of the last Get was applied, and not after a rejected
+// reload, so the rejected configuration is reported again.
+type acknowledger interface {
+	applied()
+}
+
+func acknowledge(p Provider) {
+	if a, ok := p.(acknowledger); ok {
+		a.applied()
+	}
+}
+
+// reloader holds the latest layer of every provider.
+type reloader struct {
+	base      *tree
+	providers []Provider
+
+	mu     sync.Mutex
+	layers []map[string]any
+}
+
+func (r *reloader) loop(ctx context.Context, i int, changes <-chan struct{}) {
+	p := r.providers[i]
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+		}
+
+		m, err := p.Get(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("config: %s: %v", p.Name(), err)
+			}
+			continue
+		}
+
+		r.mu.Lock()
+		prev := r.layers[i]
+		r.layers[i] = m
+		if err := r.apply(); err != nil {
+			r.layers[i] = prev
+			log.Printf("config: reload from %s rejected: %v", p.Name(), err)
+		} else {
+			acknowledge(p)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// apply decodes the Values from the loaded layers plus the provider layers
+// and publishes them if all are valid.
+func (r *reloader) apply() error {
+	secs := registeredSections()
+	dyn := make(map[string]bool)
+	for _, s := range secs {
+		if _, ok := s.ptr.(dynamic); ok {
+			dyn[s.name] = true
+		}
+	}
+
+	t := r.base.clone()
+	d := &decoder{sources: t.sources}
+	for i, m := range r.layers {
+		src := r.providers[i].Name()
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !dyn[k] {
+				d.errs = append(d.errs, fmt.Errorf("%s: not a dynamic section (from %s)", k, src))
+			}
+		}
+		t.merge(t.root, "", m, src)
+	}
+
+	var commits []func() func()
+	for _, s := range secs {
+		if dv, ok := s.ptr.(dynamic); ok {
+			raw, present := t.root[s.name]
+			if commit := dv.prepare(d, raw, present); commit != nil {
+				commits = append(commits, commit)
+			}
+		}
+	}
+	if len(d.errs) > 0 {
+		return fmt.Errorf("config: %w", errors.Join(d.errs...))
+	}
+	commitAll(commits)
+	return nil
+}
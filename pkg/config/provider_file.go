@@ -0,0 +1,94 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileDebounce merges the burst of events editors and deploy tools produce
+// when replacing a file.
+const fileDebounce = 200 * time.Millisecond
+
+// FileProvider serves a YAML, JSON or TOML file and reports when it changes,
+// e.g. a ConfigMap mounted into the container.
+type FileProvider struct {
+	path string
+}
+
+// NewFileProvider returns a Provider reading path; the format follows its
+// extension, .toml for TOML and YAML otherwise.
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: filepath.Clean(path)}
+}
+
+// Name implements Provider.
+func (p *FileProvider) Name() string {
+	return "file " + p.path
+}
+
+// Get implements Provider.
+func (p *FileProvider) Get(ctx context.Context) (map[string]any, error) {
+	b, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(b, filepath.Ext(p.path))
+}
+
+// Watch implements Provider. It watches the directory rather than the file,
+// which survives the file being replaced by a rename as editors and
+// Kubernetes do.
+func (p *FileProvider) Watch(ctx context.Context) (<-chan struct{}, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Add(filepath.Dir(p.path)); err != nil {
+		w.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer w.Close()
+		debounce := time.NewTimer(time.Hour)
+		debounce.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				debounce.Stop()
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				// Mounted ConfigMaps are updated by swapping the ..data symlink.
+				if filepath.Clean(ev.Name) == p.path || filepath.Base(ev.Name) == "..data" {
+					debounce.Reset(fileDebounce)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("config: %s: watch: %v", p.Name(), err)
+			case <-debounce.C:
+				notify(changes)
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// notify sends on a buffered channel of one without blocking; a pending
+// signal already covers the new change.
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
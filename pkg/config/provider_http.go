@@ -0,0 +1,219 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPProvider polls a config center over HTTP. The document at the URL
+// is YAML, JSON or TOML, told apart by Content-Type or the URL's extension.
+// Polls send If-None-Match when the server returned an ETag, and a change
+// is only reported when the body differs from the last one applied; a
+// rejected document is reported again on the next poll.
+type HTTPProvider struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+	header   http.Header
+
+	mu      sync.Mutex
+	current document  // last applied
+	pending *document // found by poll, returned by the next Get
+	fetched *document // returned by Get, until applied
+}
+
+// document is a fetched version of the configuration.
+type document struct {
+	etag   string
+	sum    [sha256.Size]byte
+	body   []byte
+	format string
+}
+
+// HTTPOption configures an HTTPProvider.
+type HTTPOption func(*HTTPProvider)
+
+// WithHTTPClient overrides the *http.Client used for polling.
+func WithHTTPClient(hc *http.Client) HTTPOption {
+	return func(p *HTTPProvider) {
+		p.client = hc
+	}
+}
+
+// WithHeader adds a header to every request, e.g. an access token.
+func WithHeader(key, value string) HTTPOption {
+	return func(p *HTTPProvider) {
+		p.header.Add(key, value)
+	}
+}
+
+// NewHTTPProvider returns a Provider fetching url every interval.
+func NewHTTPProvider(url string, interval time.Duration, opts ...HTTPOption) *HTTPProvider {
+	p := &HTTPProvider{
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		header:   make(http.Header),
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// Name implements Provider.
+func (p *HTTPProvider) Name() string {
+	return "http " + p.url
+}
+
+// Get implements Provider. It returns the document a poll found changed,
+// so the change is not fetched twice, or else fetches the current one.
+func (p *HTTPProvider) Get(ctx context.Context) (map[string]any, error) {
+	p.mu.Lock()
+	doc := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+
+	if doc == nil {
+		resp, body, err := p.fetch(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		doc = newDocument(resp, body)
+	}
+	m, err := parse(doc.body, doc.format)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.fetched = doc
+	p.mu.Unlock()
+	return m, nil
+}
+
+// applied records that the document returned by the last Get was applied.
+func (p *HTTPProvider) applied() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fetched != nil {
+		p.current = *p.fetched
+		p.current.body = nil
+		p.fetched = nil
+	}
+}
+
+// Watch implements Provider.
+func (p *HTTPProvider) Watch(ctx context.Context) (<-chan struct{}, error) {
+	if p.interval <= 0 {
+		return nil, fmt.Errorf("poll interval must be positive, got %s", p.interval)
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			changed, err := p.poll(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("config: %s: %v", p.Name(), err)
+				}
+				continue
+			}
+			if changed {
+				notify(changes)
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// poll reports whether the document differs from the one applied last and
+// keeps it for Get.
+func (p *HTTPProvider) poll(ctx context.Context) (bool, error) {
+	p.mu.Lock()
+	cur := p.current
+	p.mu.Unlock()
+
+	resp, body, err := p.fetch(ctx, cur.etag)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+	doc := newDocument(resp, body)
+	if doc.sum == cur.sum {
+		return false, nil
+	}
+
+	p.mu.Lock()
+	p.pending = doc
+	p.mu.Unlock()
+	return true, nil
+}
+
+func newDocument(resp *http.Response, body []byte) *document {
+	return &document{
+		etag:   resp.Header.Get("ETag"),
+		sum:    sha256.Sum256(body),
+		body:   body,
+		format: format(resp),
+	}
+}
+
+// fetch GETs the document; with etag set, 304 Not Modified is a success.
+func (p *HTTPProvider) fetch(ctx context.Context, etag string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, vs := range p.header {
+		req.Header[k] = vs
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return resp, body, nil
+	case resp.StatusCode == http.StatusNotModified && etag != "":
+		return resp, nil, nil
+	}
+	return nil, nil, fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(body))
+}
+
+// format returns the file extension matching the response.
+func format(resp *http.Response) string {
+	if strings.Contains(resp.Header.Get("Content-Type"), "toml") {
+		return ".toml"
+	}
+	return path.Ext(resp.Request.URL.Path)
+}
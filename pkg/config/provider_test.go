@@ -0,0 +1,333 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubCenter is a config center serving one YAML document. Every document
+// sent in full is reported on served.
+type stubCenter struct {
+	mu          sync.Mutex
+	body        string
+	etags       bool
+	requests    int
+	notModified int
+	served      chan string
+}
+
+func newStubCenter(body string, etags bool) *stubCenter {
+	return &stubCenter{body: body, etags: etags, served: make(chan string, 1000)}
+}
+
+func (s *stubCenter) set(body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body = body
+}
+
+func (s *stubCenter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	body := s.body
+	if s.etags {
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(body)))
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			s.notModified++
+			s.mu.Unlock()
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write([]byte(body))
+	select {
+	case s.served <- body:
+	default:
+	}
+}
+
+func TestHTTPProviderPoll(t *testing.T) {
+	for _, etags := range []bool{true, false} {
+		t.Run(fmt.Sprintf("etags=%v", etags), func(t *testing.T) {
+			stub := newStubCenter("flags:\n  beta: true\n", etags)
+			srv := httptest.NewServer(stub)
+			defer srv.Close()
+
+			ctx := context.Background()
+			p := NewHTTPProvider(srv.URL+"/dynamic.yaml", time.Hour)
+			m, err := p.Get(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if flags, _ := m["flags"].(map[string]any); flags["beta"] != true {
+				t.Fatalf("Get = %v", m)
+			}
+
+			p.applied()
+
+			if changed, err := p.poll(ctx); err != nil || changed {
+				t.Fatalf("unchanged document: changed = %v, err = %v", changed, err)
+			}
+			stub.set("flags:\n  beta: false\n")
+			if changed, err := p.poll(ctx); err != nil || !changed {
+				t.Fatalf("changed document: changed = %v, err = %v", changed, err)
+			}
+			// Get returns the polled document without fetching it again,
+			// even if the center changed since.
+			stub.set("flags:\n  beta: maybe\n")
+			m, err = p.Get(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if flags, _ := m["flags"].(map[string]any); flags["beta"] != false {
+				t.Fatalf("Get after poll = %v", m)
+			}
+			// A document that was not applied is reported again.
+			stub.set("flags:\n  beta: false\n")
+			if changed, _ := p.poll(ctx); !changed {
+				t.Fatal("rejected document not reported again")
+			}
+			if _, err := p.Get(ctx); err != nil {
+				t.Fatal(err)
+			}
+			p.applied()
+			if changed, err := p.poll(ctx); err != nil || changed {
+				t.Fatalf("after apply: changed = %v, err = %v", changed, err)
+			}
+
+			if stub.requests != 5 {
+				t.Errorf("requests = %d, want 5", stub.requests)
+			}
+			wantNotModified := 0
+			if etags {
+				wantNotModified = 2
+			}
+			if stub.notModified != wantNotModified {
+				t.Errorf("304 responses = %d, want %d", stub.notModified, wantNotModified)
+			}
+		})
+	}
+}
+
+func TestHTTPProviderWatch(t *testing.T) {
+	stub := newStubCenter("flags:\n  beta: true\n", true)
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewHTTPProvider(srv.URL+"/dynamic.yaml", 5*time.Millisecond)
+	if _, err := p.Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	p.applied()
+	changes, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changes:
+		t.Fatal("change reported for an unchanged document")
+	case <-time.After(50 * time.Millisecond):
+	}
+	stub.set("flags:\n  beta: false\n")
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change reported")
+	}
+}
+
+type testLimits struct {
+	QPS int `config:"qps"`
+}
+
+func (l *testLimits) Validate() error {
+	if l.QPS <= 0 {
+		return errors.New("qps must be positive")
+	}
+	return nil
+}
+
+type testFlags struct {
+	Beta  bool   `config:"beta"`
+	Level string `config:"level"`
+}
+
+var (
+	limitsValue = NewValue("test_limits", testLimits{QPS: 10})
+	flagsValue  = NewValue("test_flags", testFlags{Level: "info"})
+)
+
+func TestWatchReload(t *testing.T) {
+	if _, err := Load("test", "-config", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	stub := newStubCenter("test_limits:\n  qps: 20\ntest_flags:\n  level: warn\n", true)
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := Watch(ctx, NewHTTPProvider(srv.URL+"/dynamic.yaml", 5*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if l, f := limitsValue.Get(), flagsValue.Get(); l.QPS != 20 || f.Level != "warn" {
+		t.Fatalf("after Watch: %+v %+v", l, f)
+	}
+
+	type limitsChange struct {
+		old, cur testLimits
+		flags    testFlags
+	}
+	limitsChanges := make(chan limitsChange, 10)
+	flagsChanges := make(chan testFlags, 10)
+	defer limitsValue.Subscribe(func(old, cur testLimits) {
+		limitsChanges <- limitsChange{old, cur, flagsValue.Get()}
+	})()
+	defer flagsValue.Subscribe(func(_, cur testFlags) {
+		flagsChanges <- cur
+	})()
+
+	// Both sections change in one reload; subscribers see all of it.
+	stub.set("test_limits:\n  qps: 50\ntest_flags:\n  beta: true\n  level: warn\n")
+	select {
+	case c := <-limitsChanges:
+		if c.old.QPS != 20 || c.cur.QPS != 50 || !c.flags.Beta {
+			t.Fatalf("limits change %+v", c)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("reload not applied")
+	}
+	if f := <-flagsChanges; !f.Beta {
+		t.Fatalf("flags change %+v", f)
+	}
+
+	// An invalid section rejects the whole reload, valid sections included.
+	invalid := "test_limits:\n  qps: 0\ntest_flags:\n  beta: true\n  level: debug\n"
+	stub.set(invalid)
+	waitServed(t, stub, invalid)
+	stub.set("test_limits:\n  qps: 60\ntest_flags:\n  beta: true\n  level: warn\n")
+	select {
+	case c := <-limitsChanges:
+		if c.old.QPS != 50 || c.cur.QPS != 60 {
+			t.Fatalf("limits change %+v, want 50 -> 60", c)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("reload after a rejected one not applied")
+	}
+	select {
+	case f := <-flagsChanges:
+		t.Fatalf("flags changed to %+v by a rejected reload", f)
+	default:
+	}
+	if f := flagsValue.Get(); f.Level != "warn" {
+		t.Fatalf("flags %+v", f)
+	}
+
+	// Providers cannot change the configuration fixed at startup.
+	static := "test_limits:\n  qps: 70\nmysql:\n  dsn: other\n"
+	stub.set(static)
+	waitServed(t, stub, static)
+	stub.set("test_limits:\n  qps: 80\ntest_flags:\n  beta: true\n  level: warn\n")
+	select {
+	case c := <-limitsChanges:
+		if c.cur.QPS != 80 {
+			t.Fatalf("limits change %+v, want 60 -> 80", c)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("reload after a rejected one not applied")
+	}
+}
+
+// testGated is rejected while its backend is down, e.g. a downstream the
+// new settings are checked against.
+type testGated struct {
+	N int `config:"n"`
+}
+
+var (
+	gatedDown     atomic.Bool
+	gatedRejected atomic.Int32
+	gatedValue    = NewValue("test_gated", testGated{})
+)
+
+func (g *testGated) Validate() error {
+	if g.N > 0 && gatedDown.Load() {
+		gatedRejected.Add(1)
+		return errors.New("backend down")
+	}
+	return nil
+}
+
+func TestWatchRetriesRejectedDocument(t *testing.T) {
+	if _, err := Load("test", "-config", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	stub := newStubCenter("test_gated:\n  n: 0\n", true)
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := Watch(ctx, NewHTTPProvider(srv.URL+"/dynamic.yaml", 5*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan testGated, 10)
+	defer gatedValue.Subscribe(func(_, cur testGated) {
+		changes <- cur
+	})()
+
+	gatedDown.Store(true)
+	defer gatedDown.Store(false)
+	stub.set("test_gated:\n  n: 1\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for gatedRejected.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("document not rejected")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The same document is applied once it validates.
+	gatedDown.Store(false)
+	select {
+	case g := <-changes:
+		if g.N != 1 {
+			t.Fatalf("gated change %+v", g)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("rejected document not retried")
+	}
+}
+
+// waitServed waits until the provider fetched body.
+func waitServed(t *testing.T, stub *stubCenter, body string) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case b := <-stub.served:
+			if b == body {
+				return
+			}
+		case <-timeout:
+			t.Fatal("document not fetched")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Validator is implemented by configuration structs checking their values
+// after decoding. Load reports its error.
+type Validator interface {
+	Validate() error
+}
+
+var sections = struct {
+	sync.Mutex
+	m map[string]any
+}{m: make(map[string]any)}
+
+// RegisterSection declares the typed configuration section of a module under
+// the top-level key name. ptr points to a struct holding the defaults; Load
+// decodes the section's keys into it, e.g.
+//
+//	type Config struct {
+//		Timeout    time.Duration `config:"timeout"`
+//		MerchantID string        `config:"merchant_id"`
+//	}
+//
+//	var cfg = Config{Timeout: 3 * time.Second}
+//
+//	func init() { config.RegisterSection("pay", &cfg) }
+//
+// is set by pay.timeout in the config files, PAY_TIMEOUT or -pay.timeout.
+// Fields are named by their `config` tag, else by their snake_cased name.
+// Sections must be registered before Load; a name used twice, also by a
+// Value, or clashing with AppConfig panics.
+func RegisterSection(name string, ptr any) {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("config: RegisterSection %q: want a pointer to a struct, got %T", name, ptr))
+	}
+	register("RegisterSection", name, ptr)
+}
+
+// register adds a section pointer or a dynamic Value under name.
+func register(caller, name string, s any) {
+	if _, ok := appFields()[name]; ok {
+		panic(fmt.Sprintf("config: %s %q: name is reserved by AppConfig", caller, name))
+	}
+
+	sections.Lock()
+	defer sections.Unlock()
+	if _, dup := sections.m[name]; dup {
+		panic(fmt.Sprintf("config: %s: section %q registered twice", caller, name))
+	}
+	sections.m[name] = s
+}
+
+// registeredSections returns the sections sorted by name.
+func registeredSections() []section {
+	sections.Lock()
+	defer sections.Unlock()
+	out := make([]section, 0, len(sections.m))
+	for name, ptr := range sections.m {
+		out = append(out, section{name: name, ptr: ptr})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+// section is a registered section: a struct pointer or a dynamic Value.
+type section struct {
+	name string
+	ptr  any
+}
+
+// typ is the type whose keys the section accepts.
+func (s section) typ() reflect.Type {
+	if dv, ok := s.ptr.(dynamic); ok {
+		return dv.valueType()
+	}
+	return reflect.TypeOf(s.ptr)
+}
+
+// appFields returns the top-level keys of AppConfig.
+func appFields() map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeOf(AppConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		if key, ok := fieldKey(t.Field(i)); ok {
+			keys[key] = true
+		}
+	}
+	return keys
+}
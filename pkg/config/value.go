@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Value is a configuration section that can change while the process runs,
+// e.g. feature flags, rate limits or log levels. Load decodes it like a
+// section registered with RegisterSection; Watch then keeps it up to date
+// with the config center. Read it with Get on every use instead of caching
+// the result, or Subscribe to act on changes.
+type Value[T any] struct {
+	name     string
+	defaults T
+	cur      atomic.Pointer[T]
+
+	mu   sync.Mutex
+	subs map[int]func(old, cur T)
+	next int
+}
+
+// NewValue declares the dynamic section name of type T, a struct, with the
+// given defaults, e.g.
+//
+//	type Flags struct {
+//		NewCheckout bool `config:"new_checkout"`
+//		QPS         int  `config:"qps"`
+//	}
+//
+//	var flags = config.NewValue("order_flags", Flags{QPS: 100})
+//
+// Like RegisterSection it must be called before Load, and panics when T is
+// not a struct or name is already taken.
+func NewValue[T any](name string, defaults T) *Value[T] {
+	if t := reflect.TypeOf(&defaults).Elem(); t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("config: NewValue %q: want a struct, got %s", name, t))
+	}
+	v := &Value[T]{name: name, defaults: defaults, subs: make(map[int]func(old, cur T))}
+	d := defaults
+	v.cur.Store(&d)
+	register("NewValue", name, v)
+	return v
+}
+
+// Name returns the top-level key of the section.
+func (v *Value[T]) Name() string {
+	return v.name
+}
+
+// Get returns the current value. It is safe for concurrent use and never
+// observes a partially applied reload.
+func (v *Value[T]) Get() T {
+	return *v.cur.Load()
+}
+
+// Subscribe calls fn with the old and new value after every reload that
+// changes the value. fn runs on the reloading goroutine and should return
+// quickly. The returned func cancels the subscription.
+func (v *Value[T]) Subscribe(fn func(old, cur T)) (cancel func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	id := v.next
+	v.next++
+	v.subs[id] = fn
+	return func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		delete(v.subs, id)
+	}
+}
+
+func (v *Value[T]) valueType() reflect.Type {
+	return reflect.TypeOf(&v.defaults).Elem()
+}
+
+// prepare decodes raw over the defaults and validates the result without
+// publishing it, so that a reload is applied to every Value or to none.
+// commit publishes the value and returns the func notifying subscribers,
+// nil if nothing changed; callers commit every Value before notifying, so
+// subscribers see the whole reload.
+func (v *Value[T]) prepare(d *decoder, raw any, present bool) (commit func() (notify func())) {
+	next := v.defaults
+	if present {
+		n := len(d.errs)
+		d.value(v.name, raw, reflect.ValueOf(&next).Elem())
+		if len(d.errs) > n {
+			return nil
+		}
+	}
+	if c, ok := any(&next).(Validator); ok {
+		if err := c.Validate(); err != nil {
+			d.errs = append(d.errs, fmt.Errorf("%s: %w", v.name, err))
+			return nil
+		}
+	}
+	return func() func() {
+		old := v.cur.Swap(&next)
+		if reflect.DeepEqual(*old, next) {
+			return nil
+		}
+		return func() {
+			v.mu.Lock()
+			subs := make([]func(old, cur T), 0, len(v.subs))
+			for _, fn := range v.subs {
+				subs = append(subs, fn)
+			}
+			v.mu.Unlock()
+			for _, fn := range subs {
+				fn(*old, next)
+			}
+		}
+	}
+}
+
+// dynamic is the type-independent side of a Value.
+type dynamic interface {
+	valueType() reflect.Type
+	prepare(d *decoder, raw any, present bool) (commit func() (notify func()))
+}
+
+// commitAll publishes every prepared Value, then notifies subscribers.
+func commitAll(commits []func() func()) {
+	var notify []func()
+	for _, commit := range commits {
+		if n := commit(); n != nil {
+			notify = append(notify, n)
+		}
+	}
+	for _, n := range notify {
+		n()
+	}
+}
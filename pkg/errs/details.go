@@ -0,0 +1,83 @@
+package errs
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Details is the structured part of an Error. It is sent as "details" in
+// HTTP error bodies and in JSON-RPC error.data.details.
+type Details struct {
+	// Violations lists every invalid field of a request.
+	Violations []FieldViolation `json:"violations,omitempty"`
+	// Retry tells the client when the call may succeed again.
+	Retry *RetryInfo `json:"retry,omitempty"`
+	// Metadata is free-form context for the client, e.g. a balance.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// FieldViolation describes one invalid field.
+type FieldViolation struct {
+	// Field is the path of the field as the client sent it, e.g.
+	// "items[0].sku".
+	Field string `json:"field"`
+	// Rule is the failed rule, e.g. the validate tag "required".
+	Rule string `json:"rule"`
+	// Message is a human readable description.
+	Message string `json:"message"`
+}
+
+// RetryInfo tells the client how long to wait before retrying.
+type RetryInfo struct {
+	After time.Duration
+}
+
+type retryJSON struct {
+	// AfterSeconds matches the HTTP Retry-After header.
+	AfterSeconds int64 `json:"after_seconds"`
+}
+
+// MarshalJSON encodes After in whole seconds, rounded up.
+func (r RetryInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(retryJSON{AfterSeconds: int64(math.Ceil(r.After.Seconds()))})
+}
+
+func (r *RetryInfo) UnmarshalJSON(b []byte) error {
+	var v retryJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	r.After = time.Duration(v.AfterSeconds) * time.Second
+	return nil
+}
+
+func (e *Error) details() *Details {
+	if e.Details == nil {
+		e.Details = &Details{}
+	}
+	return e.Details
+}
+
+// WithViolations appends field violations.
+func (e *Error) WithViolations(v ...FieldViolation) *Error {
+	d := e.details()
+	d.Violations = append(d.Violations, v...)
+	return e
+}
+
+// WithRetry tells the client to retry after d.
+func (e *Error) WithRetry(d time.Duration) *Error {
+	e.details().Retry = &RetryInfo{After: d}
+	return e
+}
+
+// WithMetadata sets a metadata entry.
+func (e *Error) WithMetadata(key string, value any) *Error {
+	d := e.details()
+	if d.Metadata == nil {
+		d.Metadata = make(map[string]any)
+	}
+	d.Metadata[key] = value
+	return e
+}
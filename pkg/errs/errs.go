@@ -10,13 +10,26 @@ const (
     CodeBadRequest Code = "BAD_REQUEST"
     CodeNotFound   Code = "NOT_FOUND"
     CodeInternal   Code = "INTERNAL"
+
+    CodeUnauthorized Code = "UNAUTHORIZED"
+    CodeForbidden    Code = "FORBIDDEN"
+
+    CodeConflict           Code = "CONFLICT"
+    CodePreconditionFailed Code = "PRECONDITION_FAILED"
+    CodePayloadTooLarge    Code = "PAYLOAD_TOO_LARGE"
+    CodeTooManyRequests    Code = "TOO_MANY_REQUESTS"
+
+    CodeUnavailable Code = "UNAVAILABLE"
+    CodeTimeout     Code = "TIMEOUT"
 )
 
-// Error is a structured error used across HTTP/RPC boundaries.
+// Error is a structured error used across HTTP/RPC boundaries. The cause is
+// only logged, never sent to clients.
 type Error struct {
-    Code  Code   `json:"code"`
-    Msg   string `json:"message"`
-    cause error  `json:"-"`
+    Code    Code     `json:"code"`
+    Msg     string   `json:"message"`
+    Details *Details `json:"details,omitempty"`
+    cause   error    `json:"-"`
 }
 
 func (e *Error) Error() string {
@@ -32,6 +45,17 @@ func (e *Error) WithCause(err error) *Error {
     return e
 }
 
+// Cause returns the error attached with WithCause, if any.
+func (e *Error) Cause() error {
+    return e.cause
+}
+
+// New returns an error with the given code, e.g. one registered by a
+// module with Register.
+func New(code Code, msg string) *Error {
+    return &Error{Code: code, Msg: msg}
+}
+
 // Helpers to construct typed errors.
 
 func BadRequest(msg string) *Error {
@@ -45,3 +69,35 @@ func NotFound(msg string) *Error {
 func Internal(msg string) *Error {
     return &Error{Code: CodeInternal, Msg: msg}
 }
+
+func Unauthorized(msg string) *Error {
+    return &Error{Code: CodeUnauthorized, Msg: msg}
+}
+
+func Forbidden(msg string) *Error {
+    return &Error{Code: CodeForbidden, Msg: msg}
+}
+
+func Conflict(msg string) *Error {
+    return &Error{Code: CodeConflict, Msg: msg}
+}
+
+func PreconditionFailed(msg string) *Error {
+    return &Error{Code: CodePreconditionFailed, Msg: msg}
+}
+
+func PayloadTooLarge(msg string) *Error {
+    return &Error{Code: CodePayloadTooLarge, Msg: msg}
+}
+
+func TooManyRequests(msg string) *Error {
+    return &Error{Code: CodeTooManyRequests, Msg: msg}
+}
+
+func Unavailable(msg string) *Error {
+    return &Error{Code: CodeUnavailable, Msg: msg}
+}
+
+func Timeout(msg string) *Error {
+    return &Error{Code: CodeTimeout, Msg: msg}
+}
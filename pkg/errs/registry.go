@@ -0,0 +1,113 @@
+package errs
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Mapping is how a Code is reported by the transports.
+type Mapping struct {
+	// HTTPStatus is the response status of the HTTP transport.
+	HTTPStatus int
+	// RPCCode is the JSON-RPC error.code; the Code itself travels in
+	// error.data.code.
+	RPCCode int
+}
+
+// Fallbacks for codes that were never registered.
+var unknown = Mapping{HTTPStatus: http.StatusInternalServerError, RPCCode: -32000}
+
+var registry = struct {
+	sync.RWMutex
+	codes map[Code]Mapping
+	rpc   map[int]Code
+}{
+	codes: make(map[Code]Mapping),
+	rpc:   make(map[int]Code),
+}
+
+// Built-in codes. BAD_REQUEST uses JSON-RPC's "invalid params"; the others
+// take the server error range -32000..-32099, mirroring the HTTP status
+// where possible.
+func init() {
+	Register(CodeBadRequest, http.StatusBadRequest, -32602)
+	Register(CodeUnauthorized, http.StatusUnauthorized, -32001)
+	Register(CodeForbidden, http.StatusForbidden, -32003)
+	Register(CodeNotFound, http.StatusNotFound, -32004)
+	Register(CodeConflict, http.StatusConflict, -32009)
+	Register(CodePreconditionFailed, http.StatusPreconditionFailed, -32012)
+	Register(CodePayloadTooLarge, http.StatusRequestEntityTooLarge, -32013)
+	Register(CodeTooManyRequests, http.StatusTooManyRequests, -32029)
+	Register(CodeInternal, http.StatusInternalServerError, -32000)
+	Register(CodeUnavailable, http.StatusServiceUnavailable, -32053)
+	Register(CodeTimeout, http.StatusGatewayTimeout, -32054)
+}
+
+// Register adds a business code with its HTTP status and JSON-RPC code, e.g.
+// from a module's init:
+//
+//	var CodeInsufficientBalance = errs.Code("PAY_INSUFFICIENT_BALANCE")
+//
+//	func init() {
+//		errs.Register(CodeInsufficientBalance, http.StatusUnprocessableEntity, -32100)
+//	}
+//
+// Registering a code twice panics, so two modules cannot silently claim the
+// same name. Several codes may share an RPC code; FromRPCCode then returns
+// the first one registered.
+func Register(code Code, httpStatus, rpcCode int) {
+	if code == "" {
+		panic("errs: Register with empty code")
+	}
+	if httpStatus < 400 || httpStatus > 599 {
+		panic(fmt.Sprintf("errs: Register %s: HTTP status %d is not an error status", code, httpStatus))
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, dup := registry.codes[code]; dup {
+		panic(fmt.Sprintf("errs: Register called twice for code %s", code))
+	}
+	registry.codes[code] = Mapping{HTTPStatus: httpStatus, RPCCode: rpcCode}
+	if _, ok := registry.rpc[rpcCode]; !ok {
+		registry.rpc[rpcCode] = code
+	}
+}
+
+// Lookup returns the mapping of code and whether it is registered.
+func Lookup(code Code) (Mapping, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	m, ok := registry.codes[code]
+	return m, ok
+}
+
+// HTTPStatus returns the HTTP status of code; unregistered codes are 500.
+func HTTPStatus(code Code) int {
+	if m, ok := Lookup(code); ok {
+		return m.HTTPStatus
+	}
+	return unknown.HTTPStatus
+}
+
+// RPCCode returns the JSON-RPC error code of code; unregistered codes are
+// -32000.
+func RPCCode(code Code) int {
+	if m, ok := Lookup(code); ok {
+		return m.RPCCode
+	}
+	return unknown.RPCCode
+}
+
+// FromRPCCode returns the code registered for a JSON-RPC error code, for
+// peers that do not report the code in error.data. Unknown codes are
+// INTERNAL.
+func FromRPCCode(rpcCode int) Code {
+	registry.RLock()
+	defer registry.RUnlock()
+	if c, ok := registry.rpc[rpcCode]; ok {
+		return c
+	}
+	return CodeInternal
+}
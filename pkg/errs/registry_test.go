@@ -0,0 +1,95 @@
+package errs
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinMappings(t *testing.T) {
+	cases := []struct {
+		code   Code
+		status int
+		rpc    int
+	}{
+		{CodeBadRequest, http.StatusBadRequest, -32602},
+		{CodeUnauthorized, http.StatusUnauthorized, -32001},
+		{CodeForbidden, http.StatusForbidden, -32003},
+		{CodeNotFound, http.StatusNotFound, -32004},
+		{CodeConflict, http.StatusConflict, -32009},
+		{CodePreconditionFailed, http.StatusPreconditionFailed, -32012},
+		{CodePayloadTooLarge, http.StatusRequestEntityTooLarge, -32013},
+		{CodeTooManyRequests, http.StatusTooManyRequests, -32029},
+		{CodeInternal, http.StatusInternalServerError, -32000},
+		{CodeUnavailable, http.StatusServiceUnavailable, -32053},
+		{CodeTimeout, http.StatusGatewayTimeout, -32054},
+	}
+	for _, tc := range cases {
+		if got := HTTPStatus(tc.code); got != tc.status {
+			t.Errorf("HTTPStatus(%s) = %d, want %d", tc.code, got, tc.status)
+		}
+		if got := RPCCode(tc.code); got != tc.rpc {
+			t.Errorf("RPCCode(%s) = %d, want %d", tc.code, got, tc.rpc)
+		}
+		if got := FromRPCCode(tc.rpc); got != tc.code {
+			t.Errorf("FromRPCCode(%d) = %s, want %s", tc.rpc, got, tc.code)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	const (
+		insufficient Code = "TEST_INSUFFICIENT_BALANCE"
+		frozen       Code = "TEST_ACCOUNT_FROZEN"
+	)
+	Register(insufficient, http.StatusUnprocessableEntity, -32100)
+	Register(frozen, http.StatusUnprocessableEntity, -32100)
+
+	if m, ok := Lookup(insufficient); !ok || m != (Mapping{HTTPStatus: http.StatusUnprocessableEntity, RPCCode: -32100}) {
+		t.Errorf("Lookup = %+v, %v", m, ok)
+	}
+	if got := HTTPStatus(frozen); got != http.StatusUnprocessableEntity {
+		t.Errorf("HTTPStatus = %d", got)
+	}
+	// A shared RPC code maps back to the first code registered.
+	if got := FromRPCCode(-32100); got != insufficient {
+		t.Errorf("FromRPCCode(-32100) = %s, want %s", got, insufficient)
+	}
+
+	if _, ok := Lookup("TEST_UNREGISTERED"); ok {
+		t.Error("Lookup of an unregistered code succeeded")
+	}
+	if s, c := HTTPStatus("TEST_UNREGISTERED"), RPCCode("TEST_UNREGISTERED"); s != http.StatusInternalServerError || c != -32000 {
+		t.Errorf("unregistered code: %d / %d, want 500 / -32000", s, c)
+	}
+	if got := FromRPCCode(-32999); got != CodeInternal {
+		t.Errorf("FromRPCCode(-32999) = %s, want INTERNAL", got)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	cases := []struct {
+		name   string
+		code   Code
+		status int
+		want   string
+	}{
+		{"duplicate", CodeNotFound, http.StatusGone, "Register called twice for code NOT_FOUND"},
+		{"empty code", "", http.StatusBadRequest, "empty code"},
+		{"success status", "TEST_OK", http.StatusOK, "HTTP status 200 is not an error status"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				p := recover()
+				if msg, _ := p.(string); !strings.Contains(msg, tc.want) {
+					t.Fatalf("panic %v, want %q", p, tc.want)
+				}
+			}()
+			Register(tc.code, tc.status, -32199)
+		})
+	}
+	if got := HTTPStatus(CodeNotFound); got != http.StatusNotFound {
+		t.Errorf("a failed Register changed NOT_FOUND to %d", got)
+	}
+}
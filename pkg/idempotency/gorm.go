@@ -0,0 +1,127 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordModel is the GORM model mapping to the idempotency_keys table:
+//
+//	CREATE TABLE idempotency_keys (
+//	    idem_key     VARCHAR(255)  NOT NULL PRIMARY KEY,
+//	    fingerprint  CHAR(64)      NOT NULL,
+//	    done         TINYINT(1)    NOT NULL DEFAULT 0,
+//	    result       MEDIUMBLOB    NULL,
+//	    content_type VARCHAR(255)  NOT NULL DEFAULT '',
+//	    err_code     VARCHAR(64)   NOT NULL DEFAULT '',
+//	    err_msg      VARCHAR(1024) NOT NULL DEFAULT '',
+//	    err_details  BLOB          NULL,
+//	    expires_at   DATETIME(3)   NOT NULL,
+//	    KEY idx_idempotency_keys_expires_at (expires_at)
+//	);
+type recordModel struct {
+	Key         string    `gorm:"column:idem_key;primaryKey;size:255"`
+	Fingerprint string    `gorm:"column:fingerprint;size:64;not null"`
+	Done        bool      `gorm:"column:done;not null;default:false"`
+	Result      []byte    `gorm:"column:result;type:mediumblob"`
+	ContentType string    `gorm:"column:content_type;size:255;not null;default:''"`
+	ErrCode     string    `gorm:"column:err_code;size:64;not null;default:''"`
+	ErrMsg      string    `gorm:"column:err_msg;size:1024;not null;default:''"`
+	ErrDetails  []byte    `gorm:"column:err_details;type:blob"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;not null;index"`
+}
+
+func (recordModel) TableName() string { return "idempotency_keys" }
+
+func (m *recordModel) toRecord() *Record {
+	return &Record{
+		Fingerprint: m.Fingerprint,
+		Done:        m.Done,
+		Result:      m.Result,
+		ContentType: m.ContentType,
+		ErrCode:     m.ErrCode,
+		ErrMsg:      m.ErrMsg,
+		ErrDetails:  m.ErrDetails,
+		ExpiresAt:   m.ExpiresAt,
+	}
+}
+
+// GormStore is a Store backed by the idempotency_keys table, shared by every
+// replica using the same database. Expired rows are replaced on reuse; purge
+// the rest periodically with DeleteExpired.
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore returns a store using db.
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// AutoMigrate creates or updates the idempotency_keys table.
+func (s *GormStore) AutoMigrate() error {
+	return s.db.AutoMigrate(&recordModel{})
+}
+
+func (s *GormStore) Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+
+	// A concurrent Release can remove the row between the insert and the
+	// read; claiming again then succeeds.
+	for attempt := 0; attempt < 2; attempt++ {
+		if err := db.Where("idem_key = ? AND expires_at <= ?", key, now).Delete(&recordModel{}).Error; err != nil {
+			return nil, err
+		}
+
+		res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&recordModel{
+			Key:         key,
+			Fingerprint: fingerprint,
+			ExpiresAt:   now.Add(ttl),
+		})
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected > 0 {
+			return nil, nil
+		}
+
+		var m recordModel
+		err := db.Where("idem_key = ?", key).Take(&m).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return m.toRecord(), nil
+	}
+	return nil, errors.New("idempotency: key " + key + " is being released concurrently")
+}
+
+func (s *GormStore) Complete(ctx context.Context, key string, rec Record) error {
+	return s.db.WithContext(ctx).Model(&recordModel{}).
+		Where("idem_key = ?", key).
+		Updates(map[string]any{
+			"done":         true,
+			"result":       []byte(rec.Result),
+			"content_type": rec.ContentType,
+			"err_code":     rec.ErrCode,
+			"err_msg":      rec.ErrMsg,
+			"err_details":  []byte(rec.ErrDetails),
+		}).Error
+}
+
+func (s *GormStore) Release(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("idem_key = ?", key).Delete(&recordModel{}).Error
+}
+
+// DeleteExpired removes expired rows and reports how many were deleted.
+func (s *GormStore) DeleteExpired(ctx context.Context) (int64, error) {
+	res := s.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&recordModel{})
+	return res.RowsAffected, res.Error
+}
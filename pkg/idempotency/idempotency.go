@@ -0,0 +1,48 @@
+// Package idempotency stores the outcome of @Idempotent calls so retries
+// carrying the same Idempotency-Key replay the first response instead of
+// running the handler again.
+//
+// boot uses an in-process MemoryStore by default; services running more than
+// one replica should switch to GormStore (or another Store) with
+// App.SetIdempotencyStore.
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Record is the state of one idempotency key.
+type Record struct {
+	// Fingerprint identifies the request the key was first used with.
+	Fingerprint string
+	// Done is false while the first call is still running.
+	Done bool
+	// Result is the JSON-encoded data of a successful call, or the response
+	// body itself when ContentType is set.
+	Result json.RawMessage
+	// ContentType is the type of a raw response body, e.g. the text/csv of
+	// an HTTP route with a non-JSON @Produces; empty for JSON data.
+	ContentType string
+	// ErrCode / ErrMsg hold the business error of a failed call, ErrDetails
+	// its JSON-encoded errs.Details.
+	ErrCode    string
+	ErrMsg     string
+	ErrDetails json.RawMessage
+	// ExpiresAt is when the key may be reused.
+	ExpiresAt time.Time
+}
+
+// Store persists idempotency records. Implementations must be safe for
+// concurrent use and make Begin atomic across every process sharing them.
+type Store interface {
+	// Begin claims key for a call with the given fingerprint. If the key is
+	// free (or expired) it stores a pending record and returns nil; otherwise
+	// it returns the existing record untouched.
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error)
+	// Complete stores the outcome of the call that claimed key.
+	Complete(ctx context.Context, key string, rec Record) error
+	// Release forgets key, e.g. after a failure worth retrying.
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,73 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store keeping records in process memory. Expired records
+// are dropped lazily.
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]*Record
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore returns an empty in-process store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]*Record),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	if rec, ok := s.records[key]; ok && now.Before(rec.ExpiresAt) {
+		cp := *rec
+		return &cp, nil
+	}
+	s.records[key] = &Record{Fingerprint: fingerprint, ExpiresAt: now.Add(ttl)}
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, ok := s.records[key]
+	if !ok {
+		return nil
+	}
+	rec.Done = true
+	rec.Fingerprint = cur.Fingerprint
+	rec.ExpiresAt = cur.ExpiresAt
+	s.records[key] = &rec
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// sweep drops expired records at most once a minute.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for k, rec := range s.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(s.records, k)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package jsonrpc provides the client side of the JSON-RPC 2.0 protocol served
+// by boot.App. Typed per-module clients generated by bizgen build on it.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+)
+
+// Client calls JSON-RPC methods on a remote doeot service.
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+	tokenFunc  func(ctx context.Context) string
+	nextID     atomic.Int64
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient overrides the underlying *http.Client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithToken sends a static bearer token with every call.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.tokenFunc = func(context.Context) string { return token }
+	}
+}
+
+// WithTokenFunc resolves the bearer token per call, e.g. from a token cache.
+func WithTokenFunc(fn func(ctx context.Context) string) Option {
+	return func(c *Client) {
+		c.tokenFunc = fn
+	}
+}
+
+// NewClient creates a client for the JSON-RPC endpoint, e.g.
+// "http://user-svc:19001/".
+func NewClient(endpoint string, opts ...Option) *Client {
+	c := &Client{
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+type tokenKey struct{}
+
+// WithAuthToken returns a ctx whose calls carry token instead of the token
+// configured on the client, e.g. to forward the end user's credentials.
+func WithAuthToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a ctx whose calls carry key as Idempotency-Key,
+// so retries of an @Idempotent method replay the first result.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+	ID      int64  `json:"id"`
+}
+
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Code      errs.Code     `json:"code"`
+			RequestID string        `json:"request_id"`
+			Details   *errs.Details `json:"details"`
+		} `json:"data"`
+	} `json:"error"`
+}
+
+// Call invokes method with params and decodes the result into result (which
+// may be nil). The request ID and language found in ctx (biz.RequestIDFrom,
+// biz.LocaleFrom), the auth token and any idempotency key
+// (WithIdempotencyKey) are sent as headers.
+// Remote errors come back as *errs.Error, details included.
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	body, err := json.Marshal(request{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+		ID:      c.nextID.Add(1),
+	})
+	if err != nil {
+		return errs.BadRequest("encode rpc params").WithCause(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errs.Internal("build rpc request").WithCause(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if id := biz.RequestIDFrom(ctx); id != "" {
+		req.Header.Set(biz.HeaderRequestID, id)
+	}
+	if l := biz.LocaleFrom(ctx); l != "" {
+		req.Header.Set(biz.HeaderAcceptLanguage, l)
+	}
+	if token := c.token(ctx); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if key, ok := ctx.Value(idempotencyKey{}).(string); ok && key != "" {
+		req.Header.Set(biz.HeaderIdempotencyKey, key)
+	}
+
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errs.Internal("rpc call " + method + " failed").WithCause(err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp response
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return errs.Internal("rpc call " + method + " failed").
+			WithCause(fmt.Errorf("decode response (http %d): %w", httpResp.StatusCode, err))
+	}
+
+	if e := resp.Error; e != nil {
+		code := e.Data.Code
+		if code == "" {
+			code = codeFromRPC(e.Code)
+		}
+		return &errs.Error{Code: code, Msg: e.Message, Details: e.Data.Details}
+	}
+
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return errs.Internal("rpc call " + method + " failed").WithCause(fmt.Errorf("decode result: %w", err))
+	}
+	return nil
+}
+
+func (c *Client) token(ctx context.Context) string {
+	if token, ok := ctx.Value(tokenKey{}).(string); ok {
+		return strings.TrimPrefix(token, "Bearer ")
+	}
+	if c.tokenFunc != nil {
+		return c.tokenFunc(ctx)
+	}
+	return ""
+}
+
+// codeFromRPC maps JSON-RPC error codes back to errs codes for servers that
+// do not report the business code in error.data.
+func codeFromRPC(code int) errs.Code {
+	switch code {
+	case -32700, -32600:
+		return errs.CodeBadRequest
+	case -32601:
+		return errs.CodeNotFound
+	}
+	return errs.FromRPCCode(code)
+}
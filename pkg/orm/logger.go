@@ -0,0 +1,79 @@
+package orm
+
+import (
+    "context"
+    "errors"
+    "log"
+    "time"
+
+    "github.com/youbuwei/doeot-go/pkg/biz"
+    "gorm.io/gorm"
+    "gorm.io/gorm/logger"
+)
+
+// requestIDLogger is a GORM logger that tags every line with the request ID
+// found in the statement context (db.WithContext(ctx)), so SQL can be
+// correlated with HTTP access logs and RPC calls.
+type requestIDLogger struct {
+    level         logger.LogLevel
+    slowThreshold time.Duration
+}
+
+func newRequestIDLogger() *requestIDLogger {
+    return &requestIDLogger{
+        level:         logger.Warn,
+        slowThreshold: 200 * time.Millisecond,
+    }
+}
+
+func (l *requestIDLogger) LogMode(level logger.LogLevel) logger.Interface {
+    nl := *l
+    nl.level = level
+    return &nl
+}
+
+func (l *requestIDLogger) Info(ctx context.Context, msg string, args ...any) {
+    if l.level >= logger.Info {
+        l.printf(ctx, "[info] "+msg, args...)
+    }
+}
+
+func (l *requestIDLogger) Warn(ctx context.Context, msg string, args ...any) {
+    if l.level >= logger.Warn {
+        l.printf(ctx, "[warn] "+msg, args...)
+    }
+}
+
+func (l *requestIDLogger) Error(ctx context.Context, msg string, args ...any) {
+    if l.level >= logger.Error {
+        l.printf(ctx, "[error] "+msg, args...)
+    }
+}
+
+func (l *requestIDLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+    if l.level <= logger.Silent {
+        return
+    }
+
+    elapsed := time.Since(begin)
+    ms := float64(elapsed.Nanoseconds()) / 1e6
+    switch {
+    case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+        sql, rows := fc()
+        l.printf(ctx, "[error] %v [%.3fms] [rows:%d] %s", err, ms, rows, sql)
+    case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+        sql, rows := fc()
+        l.printf(ctx, "[slow >= %v] [%.3fms] [rows:%d] %s", l.slowThreshold, ms, rows, sql)
+    case l.level >= logger.Info:
+        sql, rows := fc()
+        l.printf(ctx, "[%.3fms] [rows:%d] %s", ms, rows, sql)
+    }
+}
+
+func (l *requestIDLogger) printf(ctx context.Context, format string, args ...any) {
+    if id := biz.RequestIDFrom(ctx); id != "" {
+        log.Printf("gorm: request_id=%s "+format, append([]any{id}, args...)...)
+        return
+    }
+    log.Printf("gorm: "+format, args...)
+}
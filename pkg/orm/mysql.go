@@ -11,7 +11,9 @@ import (
 
 // NewMySQL creates a *gorm.DB instance with basic pool settings.
 func NewMySQL(cfg config.MySQLConfig) *gorm.DB {
-    db, err := gorm.Open(mysql.Open(cfg.DSN), &gorm.Config{})
+    db, err := gorm.Open(mysql.Open(cfg.DSN), &gorm.Config{
+        Logger: newRequestIDLogger(),
+    })
     if err != nil {
         log.Fatalf("failed to connect mysql: %v", err)
     }
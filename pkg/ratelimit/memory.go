@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore drops buckets that have refilled.
+const sweepInterval = time.Minute
+
+// MemoryStore is a Store keeping buckets in process memory. Buckets that
+// have refilled completely are dropped, so idle callers cost nothing.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket will be full again
+}
+
+// NewMemoryStore returns an empty in-process store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes one token from the bucket named key.
+func (s *MemoryStore) Allow(_ context.Context, key string, limit Limit) (Decision, error) {
+	if !limit.Valid() {
+		return Decision{Allowed: true}, nil
+	}
+	rate := limit.perSecond()
+	burst := float64(limit.Burst)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+		b.last = now
+	}
+
+	var d Decision
+	if b.tokens >= 1 {
+		b.tokens--
+		d = Decision{Allowed: true, Remaining: int(b.tokens)}
+	} else {
+		d = Decision{RetryAfter: seconds((1 - b.tokens) / rate)}
+	}
+	b.full = now.Add(seconds((burst - b.tokens) / rate))
+	return d, nil
+}
+
+// sweep drops full buckets: a new bucket starts full, so forgetting them
+// changes nothing.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for k, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, k)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
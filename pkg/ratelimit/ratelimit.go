@@ -0,0 +1,44 @@
+// Package ratelimit provides the token-bucket stores behind @RateLimit.
+//
+// boot uses an in-process MemoryStore by default; replicas that must share
+// a budget can plug in another Store (e.g. backed by Redis) with
+// App.SetRateLimitStore.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit describes a token bucket: Count tokens are added every Period and
+// at most Burst tokens are kept.
+type Limit struct {
+	Count  int
+	Period time.Duration
+	Burst  int
+}
+
+// Valid reports whether the limit can be enforced.
+func (l Limit) Valid() bool {
+	return l.Count > 0 && l.Period > 0 && l.Burst > 0
+}
+
+// perSecond returns the refill rate in tokens per second.
+func (l Limit) perSecond() float64 {
+	return float64(l.Count) / l.Period.Seconds()
+}
+
+// Decision is the outcome of taking a token.
+type Decision struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left after this call.
+	Remaining int
+	// RetryAfter is how long a rejected caller should wait for a token.
+	RetryAfter time.Duration
+}
+
+// Store takes tokens from named buckets. Implementations must be safe for
+// concurrent use; key identifies the bucket (route plus caller).
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (Decision, error)
+}
@@ -0,0 +1,58 @@
+package validate
+
+import (
+	"context"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FuncCtx validates a field with access to the request context. Returning
+// an error (e.g. the database is down) aborts StructCtx with an INTERNAL
+// error instead of reporting the field as invalid.
+type FuncCtx func(ctx context.Context, fl validator.FieldLevel) (bool, error)
+
+// RegisterCtx registers a context-aware tag validator, e.g. one querying the
+// database. Validation stays synchronous; fn should honour ctx's deadline.
+func RegisterCtx(tag string, fn FuncCtx) error {
+	return v.RegisterValidationCtx(tag, func(ctx context.Context, fl validator.FieldLevel) bool {
+		sink := sinkFrom(ctx)
+		if sink != nil && sink.err != nil {
+			// Validation is aborted anyway; skip further I/O.
+			return true
+		}
+		ok, err := fn(ctx, fl)
+		if err != nil {
+			if sink == nil {
+				return false
+			}
+			sink.err = err
+			return true
+		}
+		return ok
+	})
+}
+
+// MustRegisterCtx registers a context-aware tag validator and panics on
+// error.
+func MustRegisterCtx(tag string, fn FuncCtx) {
+	if err := RegisterCtx(tag, fn); err != nil {
+		panic(err)
+	}
+}
+
+// errSink collects the first error of a context-aware tag during one
+// StructCtx call. Validation of a struct runs on a single goroutine.
+type errSink struct {
+	err error
+}
+
+type sinkKey struct{}
+
+func withSink(ctx context.Context, s *errSink) context.Context {
+	return context.WithValue(ctx, sinkKey{}, s)
+}
+
+func sinkFrom(ctx context.Context) *errSink {
+	s, _ := ctx.Value(sinkKey{}).(*errSink)
+	return s
+}
@@ -1,7 +1,13 @@
 package validate
 
 import (
+    "math"
+    "reflect"
     "regexp"
+    "strconv"
+    "strings"
+    "time"
+    "unicode"
 
     "github.com/go-playground/validator/v10"
 )
@@ -10,14 +16,205 @@ import (
 // Adjust according to your real requirements.
 var mobileRegexp = regexp.MustCompile(`^1[3-9]\d{9}$`)
 
+var (
+    postcodeRegexp = regexp.MustCompile(`^\d{6}$`)
+    moneyRegexp    = regexp.MustCompile(`^\d+(\.\d{1,2})?$`)
+    usernameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{2,31}$`)
+)
+
+// rule is a bundled tag with its messages ({0} is the field name).
+type rule struct {
+    tag string
+    fn  validator.Func
+    zh  string
+    en  string
+}
+
+// rules is the tag pack shared by every service. String rules let empty
+// values pass; combine them with `required` where needed.
+var rules = []rule{
+    {"mobile", isMobile, "{0}必须是有效的手机号码", "{0} must be a valid mobile number"},
+    {"idcard", stringRule(isIDCard), "{0}必须是有效的身份证号码", "{0} must be a valid Chinese ID card number"},
+    {"uscc", stringRule(isUSCC), "{0}必须是有效的统一社会信用代码", "{0} must be a valid unified social credit code"},
+    {"bankcard", stringRule(isBankCard), "{0}必须是有效的银行卡号", "{0} must be a valid bank card number"},
+    {"postcode", stringRule(postcodeRegexp.MatchString), "{0}必须是有效的邮政编码", "{0} must be a valid postal code"},
+    {"password", stringRule(isStrongPassword), "{0}长度必须为8-64位，且包含大写字母、小写字母、数字和特殊字符", "{0} must be 8-64 characters with upper and lower case letters, digits and symbols"},
+    {"money", isMoney, "{0}必须是不小于0且最多两位小数的金额", "{0} must be a non-negative amount with at most two decimals"},
+    {"snowflake", isSnowflake, "{0}必须是有效的ID", "{0} must be a valid ID"},
+    {"username", stringRule(usernameRegexp.MatchString), "{0}必须以字母开头，由3-32位字母、数字或下划线组成", "{0} must be 3-32 letters, digits or underscores starting with a letter"},
+}
+
 func init() {
-    // Register tag `mobile` for phone number validation.
-    MustRegister("mobile", func(fl validator.FieldLevel) bool {
+    for _, r := range rules {
+        MustRegister(r.tag, r.fn)
+        MustRegisterTranslation(r.tag, map[string]string{
+            LocaleZh: r.zh,
+            LocaleEn: r.en,
+        })
+    }
+}
+
+// isMobile validates tag `mobile` for phone numbers.
+func isMobile(fl validator.FieldLevel) bool {
+    v := fl.Field().String()
+    if v == "" {
+        // Let `required` handle emptiness if needed.
+        return true
+    }
+    return mobileRegexp.MatchString(v)
+}
+
+// stringRule adapts a string check to a tag validator. Empty strings pass.
+func stringRule(check func(string) bool) validator.Func {
+    return func(fl validator.FieldLevel) bool {
+        if fl.Field().Kind() != reflect.String {
+            return false
+        }
         v := fl.Field().String()
+        return v == "" || check(v)
+    }
+}
+
+var (
+    idCardWeights = [17]int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+    idCardChecks  = "10X98765432"
+)
+
+// isIDCard checks an 18-digit resident ID: region, birth date and the
+// ISO 7064 MOD 11-2 check character.
+func isIDCard(s string) bool {
+    if len(s) != 18 || s[0] == '0' {
+        return false
+    }
+    sum := 0
+    for i := 0; i < 17; i++ {
+        if s[i] < '0' || s[i] > '9' {
+            return false
+        }
+        sum += int(s[i]-'0') * idCardWeights[i]
+    }
+    if unicode.ToUpper(rune(s[17])) != rune(idCardChecks[sum%11]) {
+        return false
+    }
+    birth, err := time.Parse("20060102", s[6:14])
+    return err == nil && birth.Year() >= 1900 && !birth.After(time.Now())
+}
+
+// usccChars are the characters of a unified social credit code, I, O, S, V
+// and Z excluded; the index is the character's value.
+const usccChars = "0123456789ABCDEFGHJKLMNPQRTUWXY"
+
+var usccWeights = [17]int{1, 3, 9, 27, 19, 26, 16, 17, 20, 29, 25, 13, 8, 24, 10, 30, 28}
+
+// isUSCC checks an 18-character unified social credit code (GB 32100-2015).
+func isUSCC(s string) bool {
+    if len(s) != 18 {
+        return false
+    }
+    s = strings.ToUpper(s)
+    sum := 0
+    for i := 0; i < 17; i++ {
+        n := strings.IndexByte(usccChars, s[i])
+        if n < 0 {
+            return false
+        }
+        sum += n * usccWeights[i]
+    }
+    check := (31 - sum%31) % 31
+    return s[17] == usccChars[check]
+}
+
+// isBankCard checks a 12-19 digit card number with the Luhn algorithm.
+func isBankCard(s string) bool {
+    if len(s) < 12 || len(s) > 19 {
+        return false
+    }
+    sum := 0
+    for i := len(s) - 1; i >= 0; i-- {
+        c := s[i]
+        if c < '0' || c > '9' {
+            return false
+        }
+        d := int(c - '0')
+        if (len(s)-1-i)%2 == 1 {
+            d *= 2
+            if d > 9 {
+                d -= 9
+            }
+        }
+        sum += d
+    }
+    return sum%10 == 0
+}
+
+// isStrongPassword requires 8-64 printable ASCII characters with an upper
+// case letter, a lower case letter, a digit and a symbol.
+func isStrongPassword(s string) bool {
+    if len(s) < 8 || len(s) > 64 {
+        return false
+    }
+    var upper, lower, digit, symbol bool
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        switch {
+        case c <= ' ' || c > '~':
+            return false
+        case c >= 'A' && c <= 'Z':
+            upper = true
+        case c >= 'a' && c <= 'z':
+            lower = true
+        case c >= '0' && c <= '9':
+            digit = true
+        default:
+            symbol = true
+        }
+    }
+    return upper && lower && digit && symbol
+}
+
+// isMoney validates CNY amounts: non-negative with at most two decimals.
+// Strings ("12.30") and floats are checked; integers are taken as cents.
+func isMoney(fl validator.FieldLevel) bool {
+    f := fl.Field()
+    switch f.Kind() {
+    case reflect.String:
+        v := f.String()
+        return v == "" || moneyRegexp.MatchString(v)
+    case reflect.Float32, reflect.Float64:
+        v := f.Float()
+        if v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+            return false
+        }
+        cents := v * 100
+        return math.Abs(cents-math.Round(cents)) < 1e-6
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return f.Int() >= 0
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return true
+    }
+    return false
+}
+
+// isSnowflake validates snowflake IDs: positive int64 values, as integers
+// or as decimal strings (how they reach JavaScript clients). Zero and ""
+// pass as unset.
+func isSnowflake(fl validator.FieldLevel) bool {
+    f := fl.Field()
+    switch f.Kind() {
+    case reflect.String:
+        v := f.String()
         if v == "" {
-            // Let `required` handle emptiness if needed.
             return true
         }
-        return mobileRegexp.MatchString(v)
-    })
+        if v[0] == '0' || v[0] == '+' || v[0] == '-' {
+            return false
+        }
+        n, err := strconv.ParseInt(v, 10, 64)
+        return err == nil && n > 0
+    case reflect.Int, reflect.Int64:
+        return f.Int() >= 0
+    case reflect.Uint, reflect.Uint64:
+        return f.Uint() <= math.MaxInt64
+    }
+    return false
 }
@@ -0,0 +1,129 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/youbuwei/doeot-go/pkg/biz"
+	"github.com/youbuwei/doeot-go/pkg/errs"
+)
+
+func TestRules(t *testing.T) {
+	cases := []struct {
+		tag   string
+		value any
+		ok    bool
+	}{
+		{"mobile", "13812345678", true},
+		{"mobile", "12812345678", false},
+		{"mobile", "1381234567", false},
+
+		{"idcard", "11010519491231002X", true},
+		{"idcard", "11010519491231002x", true},
+		{"idcard", "440304200002291236", true},  // 29 Feb 2000
+		{"idcard", "110105194912310021", false}, // wrong check character
+		{"idcard", "110105200102301234", false}, // 30 Feb 2001, valid checksum
+		{"idcard", "110105209901011234", false}, // born in the future
+		{"idcard", "110105189912311237", false}, // born before 1900
+		{"idcard", "010105194912310026", false}, // no region
+		{"idcard", "11010519491231002", false},
+		{"idcard", "1101051949123100AX", false},
+		{"idcard", "", true},
+
+		{"uscc", "91350100M000100Y43", true},
+		{"uscc", "91110000600037341L", true},
+		{"uscc", "91350100m000100y43", true},
+		{"uscc", "91350100M000100Y44", false}, // wrong check character
+		{"uscc", "91350100M000100I43", false}, // I is not used
+		{"uscc", "91350100M000100Y4", false},
+
+		{"bankcard", "4111111111111111", true},
+		{"bankcard", "6222021234567890128", true},
+		{"bankcard", "123456789015", true},
+		{"bankcard", "4111111111111112", false}, // Luhn
+		{"bankcard", "6212261202011584349", false},
+		{"bankcard", "12345678903", false},          // too short
+		{"bankcard", "41111111111111111111", false}, // too long
+		{"bankcard", "4111 1111 1111 1111", false},
+
+		{"postcode", "100080", true},
+		{"postcode", "10008", false},
+		{"postcode", "1000800", false},
+		{"postcode", "10008a", false},
+
+		{"password", "Passw0rd!", true},
+		{"password", "Pa0!Pa0!", true},
+		{"password", "Pa0!Pa0", false},    // too short
+		{"password", "password1!", false}, // no upper case
+		{"password", "PASSWORD1!", false}, // no lower case
+		{"password", "Password!!", false}, // no digit
+		{"password", "Password11", false}, // no symbol
+		{"password", "Pass word1!", false},
+		{"password", "Pässword1!", false},
+
+		{"money", "12.30", true},
+		{"money", "0", true},
+		{"money", "12.345", false},
+		{"money", "-1", false},
+		{"money", "1.", false},
+		{"money", 19.99, true},
+		{"money", 0.1 + 0.2, true},
+		{"money", 1.005, false},
+		{"money", -0.01, false},
+		{"money", float32(2.5), true},
+		{"money", 1999, true},
+		{"money", -1, false},
+
+		{"snowflake", int64(1786543210987654321), true},
+		{"snowflake", "1786543210987654321", true},
+		{"snowflake", "", true},
+		{"snowflake", int64(-1), false},
+		{"snowflake", "0123", false},
+		{"snowflake", "-5", false},
+		{"snowflake", "9223372036854775808", false}, // overflows int64
+		{"snowflake", "12a", false},
+		{"snowflake", uint64(1) << 63, false},
+
+		{"username", "alice_01", true},
+		{"username", "abc", true},
+		{"username", "ab", false},
+		{"username", "1alice", false},
+		{"username", "alice-01", false},
+		{"username", "a234567890123456789012345678901234", false},
+	}
+	for _, tc := range cases {
+		err := v.Var(tc.value, tc.tag)
+		if ok := err == nil; ok != tc.ok {
+			t.Errorf("%s %#v: valid = %v, want %v", tc.tag, tc.value, ok, tc.ok)
+		}
+	}
+}
+
+func TestRuleMessages(t *testing.T) {
+	type req struct {
+		IDCard string `json:"id_card" validate:"idcard"`
+	}
+	cases := []struct {
+		locale string
+		want   string
+	}{
+		{"zh-CN,zh;q=0.9", "id_card必须是有效的身份证号码"},
+		{"en-US", "id_card must be a valid Chinese ID card number"},
+	}
+	for _, tc := range cases {
+		ctx := biz.WithLocale(context.Background(), tc.locale)
+		err := StructCtx(ctx, &req{IDCard: "110105194912310021"})
+
+		var e *errs.Error
+		if !errors.As(err, &e) || e.Code != errs.CodeBadRequest {
+			t.Fatalf("%s: got %v, want a BAD_REQUEST", tc.locale, err)
+		}
+		if e.Msg != tc.want {
+			t.Errorf("%s: message %q, want %q", tc.locale, e.Msg, tc.want)
+		}
+		if e.Details == nil || len(e.Details.Violations) != 1 || e.Details.Violations[0].Rule != "idcard" {
+			t.Errorf("%s: details %+v, want one idcard violation", tc.locale, e.Details)
+		}
+	}
+}
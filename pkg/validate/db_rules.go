@@ -0,0 +1,90 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// db is queried by DB-backed rules; see UseDB.
+var db atomic.Pointer[gorm.DB]
+
+// UseDB sets the database queried by DB-backed rules such as
+// unique_db=users.phone. boot.New calls it with the shared *gorm.DB.
+func UseDB(d *gorm.DB) {
+	db.Store(d)
+}
+
+// identRegexp restricts table and column names of DB-backed rules, which
+// are interpolated into SQL.
+var identRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func init() {
+	MustRegisterCtx("unique_db", uniqueDB)
+	MustRegisterTranslation("unique_db", map[string]string{
+		LocaleZh: "{0}已存在",
+		LocaleEn: "{0} already exists",
+	})
+}
+
+// dbColumn is the table.column parameter of a DB-backed rule.
+type dbColumn struct {
+	table, column string
+	err           error
+}
+
+// dbColumns caches parsed parameters, so each is checked once.
+var dbColumns sync.Map // param -> *dbColumn
+
+func parseDBColumn(tag, param string) *dbColumn {
+	if c, ok := dbColumns.Load(param); ok {
+		return c.(*dbColumn)
+	}
+	c := &dbColumn{}
+	table, column, ok := strings.Cut(param, ".")
+	if ok && identRegexp.MatchString(table) && identRegexp.MatchString(column) {
+		c.table, c.column = table, column
+	} else {
+		c.err = fmt.Errorf("validate: %s=%s: want table.column", tag, param)
+	}
+	actual, _ := dbColumns.LoadOrStore(param, c)
+	return actual.(*dbColumn)
+}
+
+// uniqueDB implements unique_db=table.column: no row of table may hold the
+// field's value in column, e.g.
+//
+//	Phone string `json:"phone" validate:"required,mobile,unique_db=users.phone"`
+//
+// Empty values pass; combine with required. A malformed parameter fails
+// validation with an INTERNAL error, like an unreachable database.
+func uniqueDB(ctx context.Context, fl validator.FieldLevel) (bool, error) {
+	c := parseDBColumn("unique_db", fl.Param())
+	if c.err != nil {
+		return false, c.err
+	}
+	if fl.Field().IsZero() {
+		return true, nil
+	}
+
+	d := db.Load()
+	if d == nil {
+		return false, errors.New("validate: unique_db=" + fl.Param() + ": no database, call validate.UseDB")
+	}
+	var n int64
+	err := d.WithContext(ctx).Table(c.table).
+		Where(clause.Eq{Column: clause.Column{Name: c.column}, Value: fl.Field().Interface()}).
+		Limit(1).Count(&n).Error
+	if err != nil {
+		return false, fmt.Errorf("validate: unique_db=%s: %w", fl.Param(), err)
+	}
+	return n == 0, nil
+}
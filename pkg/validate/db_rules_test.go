@@ -0,0 +1,39 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/youbuwei/doeot-go/pkg/errs"
+)
+
+func TestUniqueKeepsBuiltinMeaning(t *testing.T) {
+	type item struct{ SKU string }
+	type req struct {
+		Tags  []string `validate:"unique"`
+		Items []item   `validate:"unique=SKU"`
+	}
+	if err := Struct(&req{Tags: []string{"a", "b"}, Items: []item{{"x"}, {"y"}}}); err != nil {
+		t.Fatalf("distinct elements: %v", err)
+	}
+	if err := Struct(&req{Tags: []string{"a", "a"}}); err == nil {
+		t.Fatal("duplicate tags passed")
+	}
+	if err := Struct(&req{Items: []item{{"x"}, {"x"}}}); err == nil {
+		t.Fatal("duplicate SKUs passed")
+	}
+}
+
+func TestUniqueDBInvalidParam(t *testing.T) {
+	type req struct {
+		Phone string `validate:"unique_db=users.phone;drop"`
+	}
+	for i := 0; i < 2; i++ {
+		err := StructCtx(context.Background(), &req{Phone: "13812345678"})
+		var e *errs.Error
+		if !errors.As(err, &e) || e.Code != errs.CodeInternal {
+			t.Fatalf("got %v, want INTERNAL", err)
+		}
+	}
+}
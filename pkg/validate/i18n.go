@@ -0,0 +1,157 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	zh_translations "github.com/go-playground/validator/v10/translations/zh"
+)
+
+// Locales messages are available in.
+const (
+	LocaleEn = "en"
+	LocaleZh = "zh"
+)
+
+// uni holds one translator per locale, with the built-in tag messages.
+// It is initialised before the init funcs registering custom tags run.
+var uni = newTranslators()
+
+var defaultLocale = LocaleEn
+
+func newTranslators() *ut.UniversalTranslator {
+	enLocale := en.New()
+	u := ut.New(enLocale, enLocale, zh.New())
+
+	defaults := map[string]func(*validator.Validate, ut.Translator) error{
+		LocaleEn: en_translations.RegisterDefaultTranslations,
+		LocaleZh: zh_translations.RegisterDefaultTranslations,
+	}
+	for locale, register := range defaults {
+		trans, _ := u.GetTranslator(locale)
+		if err := register(v, trans); err != nil {
+			panic(fmt.Sprintf("validate: register %s translations: %v", locale, err))
+		}
+	}
+	v.RegisterTagNameFunc(fieldName)
+	return u
+}
+
+// SetDefaultLocale sets the language of messages for callers without a
+// supported Accept-Language. It must be called before serving requests.
+func SetDefaultLocale(locale string) error {
+	if _, ok := uni.GetTranslator(locale); !ok {
+		return fmt.Errorf("validate: unsupported locale %q", locale)
+	}
+	defaultLocale = locale
+	return nil
+}
+
+// RegisterTranslation sets the messages of tag per locale. {0} is replaced
+// by the field name and {1} by the tag parameter:
+//
+//	validate.MustRegisterTranslation("mobile", map[string]string{
+//		validate.LocaleZh: "{0}必须是有效的手机号码",
+//		validate.LocaleEn: "{0} must be a valid mobile number",
+//	})
+func RegisterTranslation(tag string, messages map[string]string) error {
+	for locale, text := range messages {
+		trans, ok := uni.GetTranslator(locale)
+		if !ok {
+			return fmt.Errorf("validate: unsupported locale %q", locale)
+		}
+		err := v.RegisterTranslation(tag, trans, func(t ut.Translator) error {
+			return t.Add(tag, text, true)
+		}, translateField)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MustRegisterTranslation registers tag messages and panics on error.
+func MustRegisterTranslation(tag string, messages map[string]string) {
+	if err := RegisterTranslation(tag, messages); err != nil {
+		panic(err)
+	}
+}
+
+func translateField(t ut.Translator, fe validator.FieldError) string {
+	return translateKey(t, fe.Tag(), fe)
+}
+
+// translateKey renders the message stored under key for fe.
+func translateKey(t ut.Translator, key string, fe validator.FieldError) string {
+	msg, err := t.T(key, fe.Field(), fe.Param())
+	if err != nil {
+		return fe.Error()
+	}
+	return msg
+}
+
+// translator returns the translator best matching an Accept-Language value.
+func translator(acceptLanguage string) ut.Translator {
+	if t, ok := uni.FindTranslator(preferredLocales(acceptLanguage)...); ok {
+		return t
+	}
+	t, _ := uni.GetTranslator(defaultLocale)
+	return t
+}
+
+// preferredLocales lists the languages of an Accept-Language value by
+// decreasing quality, each region tag followed by its base language:
+// "zh-CN,en;q=0.8" gives [zh_CN zh en].
+func preferredLocales(acceptLanguage string) []string {
+	type pref struct {
+		tag string
+		q   float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f <= 0 {
+				continue
+			}
+			q = f
+		}
+		prefs = append(prefs, pref{tag: strings.ReplaceAll(tag, "-", "_"), q: q})
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+
+	locales := make([]string, 0, 2*len(prefs))
+	for _, p := range prefs {
+		locales = append(locales, p.tag)
+		if base, _, ok := strings.Cut(p.tag, "_"); ok {
+			locales = append(locales, base)
+		}
+	}
+	return locales
+}
+
+// fieldName names fields in messages the way clients send them: the json
+// name, else the name of the request binding tag, else the Go name.
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "path", "query", "header", "cookie", "form"} {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return ""
+}
@@ -1,7 +1,14 @@
 package validate
 
 import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+
+    ut "github.com/go-playground/universal-translator"
     "github.com/go-playground/validator/v10"
+    "github.com/youbuwei/doeot-go/pkg/biz"
     "github.com/youbuwei/doeot-go/pkg/errs"
 )
 
@@ -13,30 +20,95 @@ type Custom interface {
     Validate() error
 }
 
+// CustomCtx is Custom for rules that need the request context, e.g. to query
+// the database. Failures of the I/O itself should be returned as
+// errs.Internal(...).WithCause(err); other plain errors become BAD_REQUEST.
+type CustomCtx interface {
+    ValidateCtx(ctx context.Context) error
+}
+
 // Struct validates a struct using `validate` tags + optional Custom.Validate().
-// On failure it returns an *errs.Error with CodeBadRequest.
+// On failure it returns an *errs.Error with CodeBadRequest listing every
+// failed tag as a field violation, with messages in the default locale.
 func Struct(s any) error {
+    return StructCtx(context.Background(), s)
+}
+
+// StructCtx is Struct with messages in the language the caller prefers
+// (biz.LocaleFrom(ctx), set from Accept-Language for HTTP and RPC calls).
+// ctx is passed to context-aware tags (RegisterCtx) and CustomCtx.
+func StructCtx(ctx context.Context, s any) error {
     // 1) tag-based rules
-    if err := v.Struct(s); err != nil {
-        if verrs, ok := err.(validator.ValidationErrors); ok && len(verrs) > 0 {
-            return errs.BadRequest(verrs[0].Error())
+    sink := &errSink{}
+    err := v.StructCtx(withSink(ctx, sink), s)
+    if sink.err != nil {
+        return errs.Internal("validation failed").WithCause(sink.err)
+    }
+    if err != nil {
+        var verrs validator.ValidationErrors
+        if errors.As(err, &verrs) && len(verrs) > 0 {
+            return fromValidationErrors(translator(biz.LocaleFrom(ctx)), verrs)
         }
         return errs.BadRequest(err.Error())
     }
 
-    // 2) per-request custom rules
+    // 2) per-request custom rules, cheap ones first
     if c, ok := s.(Custom); ok {
         if err := c.Validate(); err != nil {
-            if e, ok := err.(*errs.Error); ok {
-                return e
-            }
-            return errs.BadRequest(err.Error())
+            return customError(err)
+        }
+    }
+    if c, ok := s.(CustomCtx); ok {
+        if err := c.ValidateCtx(ctx); err != nil {
+            return customError(err)
         }
     }
 
     return nil
 }
 
+func customError(err error) error {
+    if e, ok := err.(*errs.Error); ok {
+        return e
+    }
+    return errs.BadRequest(err.Error())
+}
+
+// fromValidationErrors turns every failed tag into a field violation. The
+// first message doubles as the error message for clients ignoring details.
+func fromValidationErrors(trans ut.Translator, verrs validator.ValidationErrors) *errs.Error {
+    violations := make([]errs.FieldViolation, 0, len(verrs))
+    for _, fe := range verrs {
+        field := fieldPath(fe)
+        violations = append(violations, errs.FieldViolation{
+            Field:   field,
+            Rule:    fe.Tag(),
+            Message: message(trans, field, fe),
+        })
+    }
+    return errs.BadRequest(violations[0].Message).WithViolations(violations...)
+}
+
+// fieldPath is the namespace without the root struct, e.g. "items[0].sku".
+func fieldPath(fe validator.FieldError) string {
+    ns := fe.Namespace()
+    if i := strings.IndexByte(ns, '.'); i >= 0 {
+        return ns[i+1:]
+    }
+    return ns
+}
+
+// message translates fe; tags without a translation get a generic message.
+func message(trans ut.Translator, field string, fe validator.FieldError) string {
+    if msg := fe.Translate(trans); msg != fe.Error() {
+        return msg
+    }
+    if p := fe.Param(); p != "" {
+        return fmt.Sprintf("%s failed on the '%s=%s' rule", field, fe.Tag(), p)
+    }
+    return fmt.Sprintf("%s failed on the '%s' rule", field, fe.Tag())
+}
+
 // Register registers a custom tag validator, e.g. "mobile", "username".
 func Register(tag string, fn validator.Func) error {
     return v.RegisterValidation(tag, fn)